@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/tommsawyer/itbooks/postgres"
+	"github.com/urfave/cli/v2"
+)
+
+// maxRevisionValue is length of old and new values printed by history command.
+const maxRevisionValue = 60
+
+// revisionFinder is store keeping history of book changes, e.g. postgres.Store.
+type revisionFinder interface {
+	FindBookRevisions(ctx context.Context, isbn string) ([]*postgres.BookRevision, error)
+}
+
+var history = &cli.Command{
+	Name:      "history",
+	Usage:     "prints changes of book noticed by scrapers",
+	ArgsUsage: "<isbn>",
+	Flags:     postgresFlags,
+	Before:    openStore,
+	After:     closeStore,
+	Action: func(c *cli.Context) error {
+		if c.NArg() != 1 {
+			return cli.Exit("isbn of book is required", 1)
+		}
+
+		finder, ok := store.(revisionFinder)
+		if !ok {
+			return cli.Exit("history is supported only by postgres", 1)
+		}
+
+		key := bookKey(c.Args().First())
+		revisions, err := finder.FindBookRevisions(c.Context, key)
+		if err != nil {
+			return err
+		}
+
+		if len(revisions) == 0 {
+			return cli.Exit(fmt.Sprintf("no changes of book %s", key), 1)
+		}
+
+		w := tabwriter.NewWriter(c.App.Writer, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "DATE\tFIELD\tOLD\tNEW")
+		for _, revision := range revisions {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n",
+				revision.CreatedAt.Time.Format("2006-01-02 15:04"),
+				revision.Field,
+				truncate(revision.OldValue, maxRevisionValue),
+				truncate(revision.NewValue, maxRevisionValue),
+			)
+		}
+
+		return w.Flush()
+	},
+}
+
+// truncate collapses whitespace and cuts s to n runes, so long descriptions fit into table row.
+func truncate(s string, n int) string {
+	s = strings.Join(strings.Fields(s), " ")
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+
+	return string(runes[:n-1]) + "…"
+}
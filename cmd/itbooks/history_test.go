@@ -0,0 +1,15 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/matryer/is"
+)
+
+func TestTruncate(t *testing.T) {
+	is := is.New(t)
+
+	is.Equal(truncate("короткий", 10), "короткий")
+	is.Equal(truncate("очень длинное описание", 10), "очень дли…")
+	is.Equal(truncate("первая строка\nвторая", 30), "первая строка вторая")
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/tommsawyer/itbooks/isbn"
+	"github.com/urfave/cli/v2"
+)
+
+var prices = &cli.Command{
+	Name:      "prices",
+	Usage:     "prints price history of book",
+	ArgsUsage: "<isbn>",
+	Flags:     storeFlags,
+	Before:    openStore,
+	After:     closeStore,
+	Action: func(c *cli.Context) error {
+		if c.NArg() != 1 {
+			return cli.Exit("isbn of book is required", 1)
+		}
+
+		key := bookKey(c.Args().First())
+		history, err := store.FindBookPrices(c.Context, key)
+		if err != nil {
+			return err
+		}
+
+		if len(history) == 0 {
+			return cli.Exit(fmt.Sprintf("no prices of book %s", key), 1)
+		}
+
+		w := tabwriter.NewWriter(c.App.Writer, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "DATE\tPRICE\tCURRENCY")
+		for _, price := range history {
+			fmt.Fprintf(w, "%s\t%s\t%s\n",
+				price.CreatedAt.Format("2006-01-02 15:04"),
+				strconv.FormatFloat(price.Price, 'f', 2, 64),
+				price.Currency,
+			)
+		}
+
+		return w.Flush()
+	},
+}
+
+// bookKey returns key under which book with given isbn is stored.
+func bookKey(s string) string {
+	// books are stored under normalized isbn, but synthetic keys can't be parsed
+	if normalized, err := isbn.Parse(s); err == nil {
+		return normalized
+	}
+
+	return s
+}
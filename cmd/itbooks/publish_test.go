@@ -0,0 +1,224 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/matryer/is"
+	"github.com/tommsawyer/itbooks/storage"
+	"github.com/tommsawyer/itbooks/storage/memory"
+	"github.com/tommsawyer/itbooks/telegram"
+)
+
+func TestSelectBook(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+	now := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	store := memory.New()
+	publishedID, err := store.UpsertBook(ctx, storage.UpsertBookParams{ISBN: "published", Title: "published"})
+	is.NoErr(err)
+	_, err = store.AddPublication(ctx, storage.AddPublicationParams{
+		BookID:  publishedID,
+		Target:  telegramTarget,
+		Channel: "@channel",
+		Status:  storage.PublicationPublished,
+	})
+	is.NoErr(err)
+
+	releasedID, err := store.UpsertBook(ctx, storage.UpsertBookParams{ISBN: "released", Title: "released"})
+	is.NoErr(err)
+
+	upcomingID, err := store.UpsertBook(ctx, storage.UpsertBookParams{
+		ISBN:        "upcoming",
+		Title:       "upcoming",
+		ReleaseDate: now.AddDate(0, 1, 0),
+	})
+	is.NoErr(err)
+
+	book, err := selectBook(ctx, store, "", "@channel", false, now, nil)
+	is.NoErr(err)
+	is.Equal(book.ID, releasedID) // the oldest unpublished book should be selected
+
+	book, err = selectBook(ctx, store, "", "@channel", true, now, nil)
+	is.NoErr(err)
+	is.Equal(book.ID, upcomingID) // upcoming book should be preferred
+
+	book, err = selectBook(ctx, store, "published", "@channel", false, now, nil)
+	is.NoErr(err)
+	is.Equal(book.ID, publishedID) // book with given isbn should be selected even if published
+
+	_, err = selectBook(ctx, store, "unknown", "@channel", false, now, nil)
+	is.True(err != nil) // unknown isbn is an error
+}
+
+func TestSelectBookWhenAllBooksArePublished(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	store := memory.New()
+	id, err := store.UpsertBook(ctx, storage.UpsertBookParams{ISBN: "isbn", Title: "title"})
+	is.NoErr(err)
+	_, err = store.AddPublication(ctx, storage.AddPublicationParams{
+		BookID:  id,
+		Target:  telegramTarget,
+		Channel: "@channel",
+		Status:  storage.PublicationPublished,
+	})
+	is.NoErr(err)
+
+	book, err := selectBook(ctx, store, "", "@channel", false, time.Now(), nil)
+	is.NoErr(err)
+	is.True(book == nil) // nothing to publish
+
+	book, err = selectBook(ctx, store, "", "@another", false, time.Now(), nil)
+	is.NoErr(err)
+	is.Equal(book.ID, id) // book isn't published to another channel
+}
+
+func TestPublishBookRecordsPublication(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+	now := time.Now()
+
+	store := memory.New()
+	id, err := store.UpsertBook(ctx, storage.UpsertBookParams{ISBN: "isbn", Title: "title"})
+	is.NoErr(err)
+
+	book, err := selectBook(ctx, store, "", "@channel", false, now, nil)
+	is.NoErr(err)
+
+	failing := func(context.Context, string, telegram.Message) (int, bool, error) {
+		return 0, false, errors.New("telegram is down")
+	}
+	is.True(publishBook(ctx, store, failing, book, "@channel", now) != nil) // send error should be returned
+
+	book, err = selectBook(ctx, store, "", "@channel", false, now, nil)
+	is.NoErr(err)
+	is.Equal(book.ID, id) // book should be selected again after failure
+
+	var sent telegram.Message
+	sending := func(_ context.Context, channel string, msg telegram.Message) (int, bool, error) {
+		is.Equal(channel, "@channel")
+		sent = msg
+		return 42, false, nil
+	}
+	is.NoErr(publishBook(ctx, store, sending, book, "@channel", now))
+	is.Equal(sent.Title, "title")
+
+	book, err = selectBook(ctx, store, "", "@channel", false, now, nil)
+	is.NoErr(err)
+	is.True(book == nil) // published book shouldn't be selected
+
+	publications, err := store.FindPublications(ctx, id)
+	is.NoErr(err)
+	is.Equal(len(publications), 2)
+	is.Equal(publications[0].Status, storage.PublicationFailed)
+	is.Equal(publications[0].MessageID, "") // failed publication has no message
+	is.Equal(publications[1].Status, storage.PublicationPublished)
+	is.Equal(publications[1].MessageID, "42")
+	is.True(!publications[1].WithImage) // message was sent without image
+	is.Equal(publications[1].Target, telegramTarget)
+	is.Equal(publications[1].Channel, "@channel")
+}
+
+func TestPublishNextSkipsRejectedBooks(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+	now := time.Now()
+
+	store := memory.New()
+	brokenID, err := store.UpsertBook(ctx, storage.UpsertBookParams{ISBN: "broken", Title: "broken"})
+	is.NoErr(err)
+	_, err = store.UpsertBook(ctx, storage.UpsertBookParams{ISBN: "first", Title: "first"})
+	is.NoErr(err)
+	_, err = store.UpsertBook(ctx, storage.UpsertBookParams{ISBN: "second", Title: "second"})
+	is.NoErr(err)
+
+	var sent []string
+	send := func(_ context.Context, _ string, msg telegram.Message) (int, bool, error) {
+		if msg.Title == "broken" {
+			return 0, false, &telegram.Error{Code: 400, Description: "Bad Request: can't parse entities"}
+		}
+		sent = append(sent, msg.Title)
+		return len(sent), true, nil
+	}
+
+	is.NoErr(publishNext(ctx, store, send, "", "@channel", false, now))
+	is.Equal(sent, []string{"first"}) // rejected book shouldn't block the next one
+
+	is.NoErr(publishNext(ctx, store, send, "", "@channel", false, now))
+	is.Equal(sent, []string{"first", "second"})
+
+	// broken book is the only one left
+	for i := 2; i < storage.MaxFailedPublications; i++ {
+		is.True(publishNext(ctx, store, send, "", "@channel", false, now) != nil)
+	}
+
+	book, err := selectBook(ctx, store, "", "@channel", false, now, nil)
+	is.NoErr(err)
+	is.True(book == nil) // book failed too many times
+
+	is.NoErr(publishNext(ctx, store, send, "", "@channel", false, now)) // nothing to publish
+
+	publications, err := store.FindPublications(ctx, brokenID)
+	is.NoErr(err)
+	is.Equal(len(publications), storage.MaxFailedPublications)
+}
+
+func TestPublishNextStopsOnTemporaryErrors(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+	now := time.Now()
+
+	store := memory.New()
+	_, err := store.UpsertBook(ctx, storage.UpsertBookParams{ISBN: "first", Title: "first"})
+	is.NoErr(err)
+	_, err = store.UpsertBook(ctx, storage.UpsertBookParams{ISBN: "second", Title: "second"})
+	is.NoErr(err)
+
+	var attempts int
+	send := func(context.Context, string, telegram.Message) (int, bool, error) {
+		attempts++
+		return 0, false, &telegram.Error{Code: 502, Description: "Bad Gateway"}
+	}
+
+	is.True(publishNext(ctx, store, send, "", "@channel", false, now) != nil)
+	is.Equal(attempts, 1) // other books would fail the same way
+}
+
+func TestBookMessage(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+	now := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	store := memory.New()
+	_, err := store.UpsertBook(ctx, storage.UpsertBookParams{
+		ISBN:        "isbn",
+		URL:         "https://example.com/book",
+		Title:       "title",
+		Image:       "https://example.com/book.jpg",
+		Description: "description",
+		Publisher:   "publisher",
+		ReleaseDate: now.AddDate(0, 0, 10),
+		Price:       1299,
+		Currency:    "RUB",
+	})
+	is.NoErr(err)
+
+	book, err := selectBook(ctx, store, "isbn", "@channel", false, now, nil)
+	is.NoErr(err)
+
+	message := bookMessage(book, now)
+	is.Equal(message.Title, "title")
+	is.Equal(message.Subtitle, "publisher")
+	is.Equal(message.Link, "https://example.com/book")
+	is.Equal(message.ImageURL, "https://example.com/book.jpg")
+	is.Equal(message.Text, "description")
+	is.True(message.Upcoming)
+	is.True(message.ReleaseDate.Equal(now.AddDate(0, 0, 10)))
+	is.Equal(message.Price, 1299.0)
+	is.Equal(message.Currency, "RUB")
+}
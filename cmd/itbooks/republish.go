@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/tommsawyer/itbooks/postgres"
+	"github.com/tommsawyer/itbooks/storage"
+	"github.com/tommsawyer/itbooks/telegram"
+	"github.com/urfave/cli/v2"
+)
+
+// publicationEditor is store tracking which published messages don't reflect their books, e.g. postgres.Store.
+type publicationEditor interface {
+	FindOutdatedPublications(ctx context.Context, target, channel string) ([]*postgres.OutdatedPublication, error)
+	MarkPublicationEdited(ctx context.Context, id int64) error
+}
+
+// editFunc edits message previously sent to telegram channel, e.g. telegram.EditCaption.
+type editFunc func(ctx context.Context, channel string, messageID int, msg telegram.Message) error
+
+var republish = &cli.Command{
+	Name:  "republish",
+	Usage: "prints posts whose books changed title, cover, price or description after publication",
+	Flags: append([]cli.Flag{
+		&cli.StringFlag{
+			Name:    "telegram-token",
+			Usage:   "token for telegram bot, required with --edit",
+			Value:   "",
+			Aliases: []string{"t"},
+			EnvVars: []string{"TELEGRAM_TOKEN"},
+		},
+		&cli.StringFlag{
+			Name:    "telegram-channel",
+			Usage:   "name of the telegram channel where books were published",
+			Value:   "",
+			Aliases: []string{"c"},
+			EnvVars: []string{"TELEGRAM_CHANNEL"},
+		},
+		&cli.BoolFlag{
+			Name:  "edit",
+			Usage: "edit outdated posts in telegram channel instead of printing them",
+		},
+		migrateFlag,
+	}, postgresFlags...),
+	Before: combine(checkMigrations, openStore, func(c *cli.Context) error {
+		if !c.Bool("edit") {
+			return nil
+		}
+
+		return authorizeInTelegram(c)
+	}),
+	After: closeStore,
+	Action: func(c *cli.Context) error {
+		ctx := c.Context
+		now := time.Now()
+
+		editor, ok := store.(publicationEditor)
+		if !ok {
+			return cli.Exit("republish is supported only by postgres", 1)
+		}
+
+		publications, err := editor.FindOutdatedPublications(ctx, telegramTarget, c.String("telegram-channel"))
+		if err != nil {
+			return err
+		}
+
+		if len(publications) == 0 {
+			fmt.Fprintln(c.App.Writer, "all posts are up to date")
+			return nil
+		}
+
+		books := make([]*storage.Book, len(publications))
+		for i, p := range publications {
+			books[i], err = store.GetBook(ctx, sq.Eq{"id": p.BookID})
+			if err != nil {
+				return err
+			}
+		}
+
+		if !c.Bool("edit") {
+			w := tabwriter.NewWriter(c.App.Writer, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "PUBLISHED\tMESSAGE\tISBN\tTITLE\tEDIT")
+			for i, p := range publications {
+				edit := "caption"
+				switch {
+				case !p.WithImage:
+					edit = "text"
+				case p.ImageChanged:
+					edit = "image and caption"
+				}
+
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n",
+					p.PublishedAt.Format("2006-01-02 15:04"),
+					p.MessageID,
+					books[i].ISBN,
+					truncate(books[i].Title, maxRevisionValue),
+					edit,
+				)
+			}
+
+			return w.Flush()
+		}
+
+		var failed int
+		for i, p := range publications {
+			err := editPublication(ctx, editor, telegram.EditText, telegram.EditCaption, telegram.EditMedia, p, books[i], now)
+			if errors.Is(err, telegram.ErrChatNotFound) || errors.Is(err, telegram.ErrBotKicked) {
+				// other posts of channel can't be edited either
+				return channelError(err, p.Channel)
+			}
+			if err != nil {
+				log.Printf("cannot edit post of book %s: %v", books[i].ISBN, err)
+				failed++
+				continue
+			}
+
+			log.Printf("edited post of book %s", books[i].ISBN)
+		}
+
+		if failed > 0 {
+			return fmt.Errorf("cannot edit %d of %d posts", failed, len(publications))
+		}
+
+		return nil
+	},
+}
+
+// editPublication updates message of publication to reflect current book and records the edit.
+// Image of message is replaced only if cover changed, otherwise only caption is edited.
+// Message sent without image has only text, telegram can't add image to it.
+func editPublication(
+	ctx context.Context,
+	editor publicationEditor,
+	editText, editCaption, editMedia editFunc,
+	p *postgres.OutdatedPublication,
+	b *storage.Book,
+	now time.Time,
+) error {
+	messageID, err := strconv.Atoi(p.MessageID)
+	if err != nil {
+		return fmt.Errorf("invalid message id %q: %w", p.MessageID, err)
+	}
+
+	edit := editCaption
+	switch {
+	case !p.WithImage:
+		edit = editText
+	case p.ImageChanged:
+		edit = editMedia
+	}
+
+	if err := edit(ctx, p.Channel, messageID, bookMessage(b, now)); err != nil {
+		return err
+	}
+
+	return editor.MarkPublicationEdited(ctx, p.ID)
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/matryer/is"
+	"github.com/tommsawyer/itbooks/postgres"
+	"github.com/tommsawyer/itbooks/storage"
+	"github.com/tommsawyer/itbooks/telegram"
+)
+
+type fakeEditor struct {
+	edited []int64
+}
+
+func (e *fakeEditor) FindOutdatedPublications(ctx context.Context, target, channel string) ([]*postgres.OutdatedPublication, error) {
+	return nil, nil
+}
+
+func (e *fakeEditor) MarkPublicationEdited(ctx context.Context, id int64) error {
+	e.edited = append(e.edited, id)
+	return nil
+}
+
+func TestEditPublication(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	book := &storage.Book{Title: "title"}
+	publication := &postgres.OutdatedPublication{
+		Publication: storage.Publication{
+			ID:        1,
+			Channel:   "@channel",
+			MessageID: "42",
+			WithImage: true,
+		},
+	}
+
+	var edits []string
+	fakeEdit := func(name string) editFunc {
+		return func(_ context.Context, channel string, messageID int, msg telegram.Message) error {
+			is.Equal(channel, "@channel")
+			is.Equal(messageID, 42)
+			is.Equal(msg.Title, "title")
+			edits = append(edits, name)
+			return nil
+		}
+	}
+
+	editor := &fakeEditor{}
+	is.NoErr(editPublication(ctx, editor, fakeEdit("text"), fakeEdit("caption"), fakeEdit("media"), publication, book, time.Now()))
+
+	publication.ImageChanged = true
+	is.NoErr(editPublication(ctx, editor, fakeEdit("text"), fakeEdit("caption"), fakeEdit("media"), publication, book, time.Now()))
+
+	publication.WithImage = false
+	is.NoErr(editPublication(ctx, editor, fakeEdit("text"), fakeEdit("caption"), fakeEdit("media"), publication, book, time.Now()))
+
+	// image should be replaced only when cover changed, message without image has only text
+	is.Equal(edits, []string{"caption", "media", "text"})
+	is.Equal(editor.edited, []int64{1, 1, 1})
+}
+
+func TestEditPublicationFailure(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	failing := func(context.Context, string, int, telegram.Message) error { return errors.New("telegram is down") }
+	publication := &postgres.OutdatedPublication{
+		Publication: storage.Publication{ID: 1, MessageID: "42"},
+	}
+
+	editor := &fakeEditor{}
+	err := editPublication(ctx, editor, failing, failing, failing, publication, &storage.Book{}, time.Now())
+	is.True(err != nil)
+	is.Equal(len(editor.edited), 0) // failed edit shouldn't be recorded
+
+	publication.MessageID = "not a number"
+	err = editPublication(ctx, editor, failing, failing, failing, publication, &storage.Book{}, time.Now())
+	is.True(err != nil)
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/matryer/is"
+	"github.com/tommsawyer/itbooks/scraper"
+	"github.com/tommsawyer/itbooks/storage/memory"
+)
+
+func TestSaveBooks(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	books := make(chan scraper.Book, 2)
+	books <- scraper.Book{
+		URL:         "https://example.com/book",
+		ISBN:        "9785970609873",
+		Title:       "title",
+		Authors:     []string{"author"},
+		Publisher:   "publisher",
+		ReleaseDate: time.Date(2024, 3, 12, 0, 0, 0, 0, time.UTC),
+		Status:      scraper.StatusPreorder,
+		Price:       1299,
+		Currency:    "RUB",
+		Details:     map[string]string{"pages": "320"},
+	}
+	books <- scraper.Book{
+		URL:   "https://example.com/book",
+		ISBN:  "9785970609873",
+		Title: "updated title",
+	}
+	close(books)
+
+	store := memory.New()
+	is.NoErr(saveBooks(ctx, store, books))
+
+	stored, err := store.FindBooks(ctx, nil)
+	is.NoErr(err)
+	is.Equal(len(stored), 1) // the same book should be updated
+
+	book := stored[0]
+	is.Equal(book.Title, "updated title")
+	is.Equal(book.Price, 1299.0) // unknown price shouldn't erase stored one
+
+	book, err = store.GetBook(ctx, sq.Eq{"isbn": "9785970609873"})
+	is.NoErr(err)
+	is.Equal(book.URL, "https://example.com/book")
+}
+
+func TestKnownBooks(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	books := make(chan scraper.Book, 1)
+	books <- scraper.Book{URL: "https://example.com/book", ISBN: "9785970609873", Title: "title"}
+	close(books)
+
+	store := memory.New()
+	is.NoErr(saveBooks(ctx, store, books))
+
+	known, err := knownBooks(ctx, store, time.Now().Add(-time.Hour))
+	is.NoErr(err)
+
+	is.True(known.KnownURL("https://example.com/book"))
+	is.True(known.KnownISBN("9785970609873"))
+	is.True(!known.KnownURL("https://example.com/other"))
+
+	known, err = knownBooks(ctx, store, time.Now().Add(time.Hour))
+	is.NoErr(err)
+	is.True(!known.KnownURL("https://example.com/book")) // book should be refreshed
+}
+
+func TestScrapeRefreshesKnownBooks(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	price := "1299 ₽"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/books":
+			fmt.Fprint(w, `<html><body><a class="book" href="/book">book</a></body></html>`)
+		case "/book":
+			fmt.Fprintf(w, `<html><body><div class="card"><h1>title</h1><p class="isbn">978-5-97060-987-3</p><span class="price">%s</span></div></body></html>`, price)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	site, err := scraper.Definition{
+		Name:      "refresh-test",
+		Publisher: "publisher",
+		StartURL:  server.URL + "/books",
+		Links:     "a.book",
+		Book:      ".card",
+		Fields: scraper.Fields{
+			ISBN:  scraper.Field{Selector: ".isbn"},
+			Title: scraper.Field{Selector: "h1"},
+			Price: scraper.Field{Selector: ".price"},
+		},
+	}.Site()
+	is.NoErr(err)
+	scraper.Register("refresh-test", site)
+
+	config := scraper.DefaultConfig
+	config.Delay = 0
+	scraper.Configure(config)
+	defer scraper.Configure(scraper.DefaultConfig)
+
+	store := memory.New()
+	scrapeWithKnown := func(updatedAfter time.Time) {
+		known, err := knownBooks(ctx, store, updatedAfter)
+		is.NoErr(err)
+
+		books, report := scrapeSites(scraper.WithKnown(ctx, known), []string{"refresh-test"})
+		is.NoErr(saveBooks(ctx, store, books))
+		is.NoErr(checkReport(report))
+	}
+
+	scrapeWithKnown(time.Now())
+
+	price = "1499 ₽"
+	scrapeWithKnown(time.Now().Add(-time.Hour)) // book was scraped recently, so it's skipped
+
+	prices, err := store.FindBookPrices(ctx, "9785970609873")
+	is.NoErr(err)
+	is.Equal(len(prices), 1)
+
+	scrapeWithKnown(time.Now()) // book should be scraped again
+
+	prices, err = store.FindBookPrices(ctx, "9785970609873")
+	is.NoErr(err)
+	is.Equal(len(prices), 2)
+	is.Equal(prices[1].Price, 1499.0) // changed price should reach the store
+
+	book, err := store.GetBook(ctx, sq.Eq{"isbn": "9785970609873"})
+	is.NoErr(err)
+	is.Equal(book.Price, 1499.0)
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/tommsawyer/itbooks/storage"
+	"github.com/urfave/cli/v2"
+)
+
+// bookSearcher is store supporting full-text search, e.g. postgres.Store.
+type bookSearcher interface {
+	SearchBooks(ctx context.Context, query string, limit int) ([]*storage.Book, error)
+}
+
+var search = &cli.Command{
+	Name:      "search",
+	Usage:     "searches books by title, authors and description",
+	ArgsUsage: "<query>",
+	Flags: append([]cli.Flag{
+		&cli.IntFlag{
+			Name:  "limit",
+			Usage: "maximum number of books to print",
+			Value: 10,
+		},
+	}, postgresFlags...),
+	Before: openStore,
+	After:  closeStore,
+	Action: func(c *cli.Context) error {
+		if !c.Args().Present() {
+			return cli.Exit("search query is required", 1)
+		}
+
+		searcher, ok := store.(bookSearcher)
+		if !ok {
+			return cli.Exit("search is supported only by postgres", 1)
+		}
+
+		books, err := searcher.SearchBooks(c.Context, strings.Join(c.Args().Slice(), " "), c.Int("limit"))
+		if err != nil {
+			return err
+		}
+
+		if len(books) == 0 {
+			fmt.Fprintln(c.App.Writer, "no books found")
+			return nil
+		}
+
+		w := tabwriter.NewWriter(c.App.Writer, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ISBN\tTITLE\tAUTHORS\tPUBLISHER")
+		for _, book := range books {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n",
+				book.ISBN,
+				book.Title,
+				strings.Join(book.Authors, ", "),
+				book.Publisher,
+			)
+		}
+
+		return w.Flush()
+	},
+}
@@ -0,0 +1,111 @@
+// Package isbn parses and validates book ISBNs.
+//
+// All ISBNs are normalized to ISBN-13 without hyphens,
+// so the same book has the same key regardless of publisher formatting.
+package isbn
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+)
+
+// SyntheticPrefix is prefix of keys for books without valid ISBN.
+const SyntheticPrefix = "url:"
+
+// ErrInvalid is returned when string is not valid ISBN.
+var ErrInvalid = errors.New("invalid isbn")
+
+// candidates matches sequences that look like ISBN-10 or ISBN-13 with optional hyphens.
+var candidates = regexp.MustCompile(`\b(?:\d-?){9}(?:(?:\d-?){3})?[\dXx]\b`)
+
+// Parse parses ISBN-10 or ISBN-13 and returns it as ISBN-13 without hyphens.
+//
+// It accepts common formatting like "ISBN 978-5-4461-2246-2" or "5-4461-2246-X".
+func Parse(s string) (string, error) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	if strings.HasPrefix(s, "ISBN") {
+		s = strings.TrimPrefix(s, "ISBN")
+		s = strings.TrimPrefix(strings.TrimPrefix(s, "-13"), "-10")
+		s = strings.TrimLeft(s, ": ")
+	}
+
+	digits := strings.Map(func(r rune) rune {
+		if r == '-' || r == ' ' {
+			return -1
+		}
+		return r
+	}, s)
+
+	switch {
+	case len(digits) == 13 && isDigits(digits) && checksum13(digits[:12]) == digits[12]:
+		return digits, nil
+	case len(digits) == 10 && isDigits(digits[:9]) && checksum10(digits[:9]) == digits[9]:
+		isbn := "978" + digits[:9]
+		return isbn + string(checksum13(isbn)), nil
+	default:
+		return "", ErrInvalid
+	}
+}
+
+// Find returns first valid ISBN in text as ISBN-13 without hyphens.
+func Find(text string) (string, bool) {
+	for _, candidate := range candidates.FindAllString(text, -1) {
+		if isbn, err := Parse(candidate); err == nil {
+			return isbn, true
+		}
+	}
+
+	return "", false
+}
+
+// Synthetic returns key for book without valid ISBN.
+// Key is book url without scheme prefixed with SyntheticPrefix.
+func Synthetic(bookURL string) string {
+	u := strings.TrimPrefix(strings.TrimPrefix(bookURL, "https://"), "http://")
+	return SyntheticPrefix + u
+}
+
+// IsSynthetic reports whether key was created by Synthetic.
+func IsSynthetic(key string) bool {
+	return strings.HasPrefix(key, SyntheticPrefix)
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+
+	return true
+}
+
+// checksum13 returns check digit for first 12 digits of ISBN-13.
+func checksum13(digits string) byte {
+	sum := 0
+	for i, r := range digits {
+		weight := 1
+		if i%2 == 1 {
+			weight = 3
+		}
+		sum += int(r-'0') * weight
+	}
+
+	return byte('0' + (10-sum%10)%10)
+}
+
+// checksum10 returns check digit for first 9 digits of ISBN-10.
+func checksum10(digits string) byte {
+	sum := 0
+	for i, r := range digits {
+		sum += int(r-'0') * (10 - i)
+	}
+
+	check := (11 - sum%11) % 11
+	if check == 10 {
+		return 'X'
+	}
+
+	return byte('0' + check)
+}
@@ -0,0 +1,64 @@
+package isbn
+
+import (
+	"testing"
+
+	"github.com/matryer/is"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		input string
+		isbn  string
+		err   error
+	}{
+		{input: "9785446122462", isbn: "9785446122462"},
+		{input: "978-5-4461-2246-2", isbn: "9785446122462"},
+		{input: " ISBN 978-5-4461-2246-2 ", isbn: "9785446122462"},
+		{input: "ISBN-13: 978-5-4461-2246-2", isbn: "9785446122462"},
+		{input: "0-306-40615-2", isbn: "9780306406157"},
+		{input: "ISBN-10: 0-8044-2957-X", isbn: "9780804429573"},
+		{input: "0-8044-2957-x", isbn: "9780804429573"},
+		{input: "978-5-4461-2246-3", err: ErrInvalid},
+		{input: "0-306-40615-3", err: ErrInvalid},
+		{input: "go-na-praktike", err: ErrInvalid},
+		{input: "97854461224", err: ErrInvalid},
+		{input: "", err: ErrInvalid},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			is := is.New(t)
+
+			isbn, err := Parse(tt.input)
+
+			is.Equal(err, tt.err)
+			is.Equal(isbn, tt.isbn)
+		})
+	}
+}
+
+func TestFind(t *testing.T) {
+	is := is.New(t)
+
+	isbn, ok := Find("Год: 2024\nСтраниц: 400\nISBN: 978-5-97060-987-3\nФормат: 70x100/16")
+	is.True(ok)
+	is.Equal(isbn, "9785970609873")
+
+	isbn, ok = Find("Артикул 1234567890123, ISBN 0-306-40615-2")
+	is.True(ok) // invalid candidates are skipped
+	is.Equal(isbn, "9780306406157")
+
+	_, ok = Find("no isbn here, only 2024 year")
+	is.True(!ok)
+}
+
+func TestSynthetic(t *testing.T) {
+	is := is.New(t)
+
+	key := Synthetic("https://dmkpress.com/catalog/computer/programming/go-na-praktike/")
+
+	is.Equal(key, "url:dmkpress.com/catalog/computer/programming/go-na-praktike/")
+	is.True(IsSynthetic(key))
+	is.True(!IsSynthetic("9785970609873"))
+}
@@ -0,0 +1,155 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/tommsawyer/itbooks/storage"
+)
+
+// Author represents authors table in postgres.
+//
+// Authors are matched by normalized name, which ignores case, "ё" and dots after initials,
+// so the same author is shared by books of different publishers.
+type Author struct {
+	ID             int64  `db:"id"`
+	Name           string `db:"name"`
+	NormalizedName string `db:"normalized_name"`
+}
+
+// translatorCredit matches translator credit that publishers append to authors,
+// e.g. "Мартин Р. Перевод Иванов И." on dmkpress.
+var translatorCredit = regexp.MustCompile(`(?s)[\s,;]*(^|[\s,;])([Пп]еревод|[Пп]ер\.|[Tt]ranslated by)([\s:].*)?$`)
+
+// cleanName collapses whitespace in name.
+func cleanName(name string) string {
+	return strings.Join(strings.Fields(name), " ")
+}
+
+// cleanAuthorName removes translator credit from author name and collapses whitespace.
+func cleanAuthorName(name string) string {
+	return cleanName(translatorCredit.ReplaceAllString(name, ""))
+}
+
+// upsertName stores name in authors or publishers table unless the same normalized name
+// is already stored, and returns id of the row.
+func (s *Store) upsertName(ctx context.Context, table, name string) (int64, error) {
+	query, args, err := psql.Insert(table).
+		Columns("name").
+		Values(name).
+		Suffix("ON CONFLICT (normalized_name) DO UPDATE SET name = " + table + ".name").
+		Suffix("RETURNING id").
+		ToSql()
+	if err != nil {
+		return 0, err
+	}
+
+	var id int64
+	if err := s.db(ctx).QueryRow(ctx, query, args...).Scan(&id); err != nil {
+		return 0, fmt.Errorf("cannot upsert %s: %w", table, err)
+	}
+
+	return id, nil
+}
+
+// upsertPublisher returns id of publisher with given name, creating it if needed.
+// It returns false if name is empty.
+func (s *Store) upsertPublisher(ctx context.Context, name string) (int64, bool, error) {
+	name = cleanName(name)
+	if name == "" {
+		return 0, false, nil
+	}
+
+	id, err := s.upsertName(ctx, "publishers", name)
+	return id, err == nil, err
+}
+
+// linkAuthors replaces authors of book with given ones, keeping their order.
+func (s *Store) linkAuthors(ctx context.Context, bookID int64, authors []string) error {
+	query, args, err := psql.Delete("book_authors").Where(sq.Eq{"book_id": bookID}).ToSql()
+	if err != nil {
+		return err
+	}
+
+	if _, err := s.db(ctx).Exec(ctx, query, args...); err != nil {
+		return fmt.Errorf("cannot unlink authors: %w", err)
+	}
+
+	for position, name := range authors {
+		name = cleanAuthorName(name)
+		if name == "" {
+			continue
+		}
+
+		authorID, err := s.upsertName(ctx, "authors", name)
+		if err != nil {
+			return err
+		}
+
+		query, args, err := psql.Insert("book_authors").
+			Columns("book_id", "author_id", "position").
+			Values(bookID, authorID, position+1).
+			Suffix("ON CONFLICT DO NOTHING").
+			ToSql()
+		if err != nil {
+			return err
+		}
+
+		if _, err := s.db(ctx).Exec(ctx, query, args...); err != nil {
+			return fmt.Errorf("cannot link author: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// FindBookAuthors returns authors of book in the order they are listed on the book.
+func (s *Store) FindBookAuthors(ctx context.Context, bookID int64) ([]*Author, error) {
+	query, args, err := psql.Select("a.id", "a.name", "a.normalized_name").
+		From("authors a").
+		Join("book_authors ba ON ba.author_id = a.id").
+		Where(sq.Eq{"ba.book_id": bookID}).
+		OrderBy("ba.position").
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db(ctx).Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("cannot find book authors: %w", err)
+	}
+	defer rows.Close()
+
+	var authors []*Author
+	for rows.Next() {
+		var author Author
+		if err := rows.Scan(&author.ID, &author.Name, &author.NormalizedName); err != nil {
+			return nil, fmt.Errorf("cannot scan author: %w", err)
+		}
+
+		authors = append(authors, &author)
+	}
+
+	return authors, rows.Err()
+}
+
+// FindBooksByAuthor returns books of author with given name.
+// Name is matched like stored names, e.g. "иванов и" finds books of "Иванов И.".
+func (s *Store) FindBooksByAuthor(ctx context.Context, name string) ([]*storage.Book, error) {
+	return s.FindBooks(ctx, sq.Expr(
+		"id IN (SELECT ba.book_id FROM book_authors ba JOIN authors a ON a.id = ba.author_id WHERE a.normalized_name = normalize_name(?))",
+		cleanAuthorName(name),
+	))
+}
+
+// FindBooksByPublisher returns books of publisher with given name.
+func (s *Store) FindBooksByPublisher(ctx context.Context, name string) ([]*storage.Book, error) {
+	return s.FindBooks(ctx, sq.Expr(
+		"publisher_id IN (SELECT id FROM publishers WHERE normalized_name = normalize_name(?))",
+		cleanName(name),
+	))
+}
@@ -0,0 +1,88 @@
+package postgres
+
+import (
+	"testing"
+
+	"github.com/matryer/is"
+	"github.com/tommsawyer/itbooks/storage"
+)
+
+func TestCleanAuthorName(t *testing.T) {
+	is := is.New(t)
+
+	is.Equal(cleanAuthorName(" Батчер  М. "), "Батчер М.")
+	is.Equal(cleanAuthorName("Батчер М. Перевод Рагимов Р. Н."), "Батчер М.")
+	is.Equal(cleanAuthorName("Мартин Р., пер. с англ. Иванов И."), "Мартин Р.")
+	is.Equal(cleanAuthorName("Donovan A. Translated by Kernighan B."), "Donovan A.")
+	is.Equal(cleanAuthorName("Перевод Иванов И."), "")
+	is.Equal(cleanAuthorName("Переводчиков П."), "Переводчиков П.")
+}
+
+func TestUpsertBookLinksAuthorsAcrossPublishers(t *testing.T) {
+	ctx, is, rollback := testTransaction(t)
+	defer rollback()
+
+	firstID, err := store.UpsertBook(ctx, storage.UpsertBookParams{
+		ISBN:      "isbn",
+		Title:     "title",
+		Authors:   []string{"Мартин Р. Перевод Иванов И.", "Фаулер М."},
+		Publisher: "ДМК Пресс",
+	})
+	is.NoErr(err)
+
+	secondID, err := store.UpsertBook(ctx, storage.UpsertBookParams{
+		ISBN:      "isbn2",
+		Title:     "title2",
+		Authors:   []string{"мартин р"},
+		Publisher: "Питер",
+	})
+	is.NoErr(err)
+
+	authors, err := store.FindBookAuthors(ctx, firstID)
+	is.NoErr(err)
+	is.Equal(len(authors), 2)
+	is.Equal(authors[0].Name, "Мартин Р.") // translator is not an author
+	is.Equal(authors[1].Name, "Фаулер М.")
+
+	authors, err = store.FindBookAuthors(ctx, secondID)
+	is.NoErr(err)
+	is.Equal(len(authors), 1)
+	is.Equal(authors[0].Name, "Мартин Р.") // author is shared by publishers
+
+	books, err := store.FindBooksByAuthor(ctx, "Мартин Р.")
+	is.NoErr(err)
+	is.Equal(len(books), 2)
+	is.Equal(books[0].ID, firstID)
+	is.Equal(books[1].ID, secondID)
+
+	books, err = store.FindBooksByPublisher(ctx, "питер")
+	is.NoErr(err)
+	is.Equal(len(books), 1)
+	is.Equal(books[0].ID, secondID)
+}
+
+func TestUpsertBookReplacesAuthors(t *testing.T) {
+	ctx, is, rollback := testTransaction(t)
+	defer rollback()
+
+	params := storage.UpsertBookParams{
+		ISBN:    "isbn",
+		Title:   "title",
+		Authors: []string{"Мартин Р."},
+	}
+	id, err := store.UpsertBook(ctx, params)
+	is.NoErr(err)
+
+	params.Authors = []string{"Фаулер М."}
+	_, err = store.UpsertBook(ctx, params)
+	is.NoErr(err)
+
+	authors, err := store.FindBookAuthors(ctx, id)
+	is.NoErr(err)
+	is.Equal(len(authors), 1)
+	is.Equal(authors[0].Name, "Фаулер М.")
+
+	books, err := store.FindBooksByAuthor(ctx, "Мартин Р.")
+	is.NoErr(err)
+	is.Equal(len(books), 0) // old author is unlinked
+}
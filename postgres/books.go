@@ -2,12 +2,15 @@ package postgres
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
 	sq "github.com/Masterminds/squirrel"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/tommsawyer/itbooks/isbn"
+	"github.com/tommsawyer/itbooks/storage"
 )
 
 var bookColumns = []string{
@@ -20,64 +23,116 @@ var bookColumns = []string{
 	"authors",
 	"properties",
 	"publisher",
+	"release_date",
+	"status",
+	"price",
+	"currency",
 	"created_at",
 	"updated_at",
 }
 
-// Book represents book table in postgres.
-type Book struct {
-	ID          int64                     `db:"id"`
-	ISBN        pgtype.Text               `db:"isbn"`
-	URL         pgtype.Text               `db:"url"`
-	Title       pgtype.Text               `db:"title"`
-	Image       pgtype.Text               `db:"image"`
-	Description pgtype.Text               `db:"description"`
-	Authors     pgtype.Array[pgtype.Text] `db:"authors"`
-	Publisher   pgtype.Text               `db:"publisher"`
-	Properties  map[string]string         `db:"properties"`
-	CreatedAt   pgtype.Timestamp          `db:"created_at"`
-	UpdatedAt   pgtype.Timestamp          `db:"updated_at"`
-}
+// scanBook scans row selected with bookColumns.
+func scanBook(row pgx.Row) (*storage.Book, error) {
+	var (
+		b           storage.Book
+		authors     pgtype.Array[pgtype.Text]
+		releaseDate pgtype.Date
+		status      pgtype.Text
+		price       pgtype.Float8
+		currency    pgtype.Text
+	)
 
-func (b *Book) scan(row pgx.Row) error {
-	return row.Scan(
+	err := row.Scan(
 		&b.ID,
 		&b.ISBN,
 		&b.URL,
 		&b.Title,
 		&b.Image,
 		&b.Description,
-		&b.Authors,
+		&authors,
 		&b.Properties,
 		&b.Publisher,
+		&releaseDate,
+		&status,
+		&price,
+		&currency,
 		&b.CreatedAt,
 		&b.UpdatedAt,
 	)
-}
+	if err != nil {
+		return nil, err
+	}
+
+	for _, author := range authors.Elements {
+		b.Authors = append(b.Authors, author.String)
+	}
+	b.ReleaseDate = releaseDate.Time
+	b.Status = status.String
+	b.Price = price.Float64
+	b.Currency = currency.String
 
-// UpsertBookParams is parameters required for inserting book.
-type UpsertBookParams struct {
-	ISBN        string
-	URL         string
-	Title       string
-	Image       string
-	Description string
-	Authors     []string
-	Publisher   string
-	Properties  map[string]string
+	return &b, nil
 }
 
 // UpsertBook creates book in postgres and returns ID.
 //
 // If row with the same ISBN already exists it will just update fields of existing row
-// and returns id of old book.
-func UpsertBook(ctx context.Context, params UpsertBookParams) (int64, error) {
+// and returns id of old book. Changed fields are recorded, see FindBookRevisions.
+func (s *Store) UpsertBook(ctx context.Context, params storage.UpsertBookParams) (int64, error) {
+	var id int64
+
+	err := s.inTransaction(ctx, func(ctx context.Context) error {
+		if err := s.adoptSyntheticBook(ctx, params); err != nil {
+			return err
+		}
+
+		old, err := s.lockBook(ctx, params.ISBN)
+		if err != nil {
+			return err
+		}
+
+		publisherID, hasPublisher, err := s.upsertPublisher(ctx, params.Publisher)
+		if err != nil {
+			return err
+		}
+
+		id, err = s.upsertBook(ctx, params, pgtype.Int8{Int64: publisherID, Valid: hasPublisher})
+		if err != nil {
+			return err
+		}
+
+		if old != nil {
+			if err := s.addBookRevisions(ctx, id, bookChanges(old, params)); err != nil {
+				return err
+			}
+		}
+
+		if err := s.linkAuthors(ctx, id, params.Authors); err != nil {
+			return err
+		}
+
+		if params.Price > 0 {
+			return s.addBookPrice(ctx, id, params.Price, params.Currency)
+		}
+
+		return nil
+	})
+
+	return id, err
+}
+
+func (s *Store) upsertBook(ctx context.Context, params storage.UpsertBookParams, publisherID pgtype.Int8) (int64, error) {
 	query, args, err := psql.Insert("books").Columns(
 		"isbn", "url", "title", "image",
-		"description", "authors", "properties", "publisher",
+		"description", "authors", "properties", "publisher", "publisher_id",
+		"release_date", "status", "price", "currency",
 	).Values(
 		params.ISBN, params.URL, params.Title, params.Image,
-		params.Description, params.Authors, params.Properties, params.Publisher,
+		params.Description, params.Authors, params.Properties, params.Publisher, publisherID,
+		pgtype.Date{Time: params.ReleaseDate, Valid: !params.ReleaseDate.IsZero()},
+		pgtype.Text{String: params.Status, Valid: params.Status != ""},
+		pgtype.Float8{Float64: params.Price, Valid: params.Price > 0},
+		pgtype.Text{String: params.Currency, Valid: params.Price > 0},
 	).Suffix(`
       ON CONFLICT(isbn) DO UPDATE 
       SET 
@@ -87,7 +142,13 @@ func UpsertBook(ctx context.Context, params UpsertBookParams) (int64, error) {
         authors=EXCLUDED.authors,
         properties=EXCLUDED.properties,
         publisher=EXCLUDED.publisher,
-        description=EXCLUDED.description
+        publisher_id=EXCLUDED.publisher_id,
+        description=EXCLUDED.description,
+        release_date=EXCLUDED.release_date,
+        status=EXCLUDED.status,
+        price=COALESCE(EXCLUDED.price, books.price),
+        currency=COALESCE(EXCLUDED.currency, books.currency),
+        updated_at=NOW()
     `,
 	).Suffix("RETURNING id").ToSql()
 	if err != nil {
@@ -96,7 +157,7 @@ func UpsertBook(ctx context.Context, params UpsertBookParams) (int64, error) {
 
 	var id int64
 
-	err = getDB(ctx).QueryRow(ctx, query, args...).Scan(&id)
+	err = s.db(ctx).QueryRow(ctx, query, args...).Scan(&id)
 	if err != nil {
 		return 0, fmt.Errorf("cannot create book: %w", err)
 	}
@@ -104,8 +165,34 @@ func UpsertBook(ctx context.Context, params UpsertBookParams) (int64, error) {
 	return id, nil
 }
 
+// adoptSyntheticBook moves book stored under synthetic key to its real ISBN.
+//
+// Book is stored under synthetic key when its ISBN could not be parsed.
+// Once ISBN is known, it should update the same row instead of creating duplicate.
+func (s *Store) adoptSyntheticBook(ctx context.Context, params storage.UpsertBookParams) error {
+	if isbn.IsSynthetic(params.ISBN) || params.URL == "" {
+		return nil
+	}
+
+	query, args, err := psql.Update("books").
+		Set("isbn", params.ISBN).
+		Where(sq.Eq{"url": params.URL}).
+		Where(sq.Like{"isbn": isbn.SyntheticPrefix + "%"}).
+		Where("NOT EXISTS (SELECT 1 FROM books WHERE isbn = ?)", params.ISBN).
+		ToSql()
+	if err != nil {
+		return err
+	}
+
+	if _, err := s.db(ctx).Exec(ctx, query, args...); err != nil {
+		return fmt.Errorf("cannot adopt book with synthetic isbn: %w", err)
+	}
+
+	return nil
+}
+
 // UpdateBook updates given fields on book.
-func UpdateBook(ctx context.Context, id int64, fields Fields) error {
+func (s *Store) UpdateBook(ctx context.Context, id int64, fields Fields) error {
 	query, params, err := psql.Update("books").
 		SetMap(fields).
 		Set("updated_at", time.Now().UTC()).
@@ -114,33 +201,39 @@ func UpdateBook(ctx context.Context, id int64, fields Fields) error {
 		return err
 	}
 
-	_, err = getDB(ctx).Exec(ctx, query, params...)
+	_, err = s.db(ctx).Exec(ctx, query, params...)
 	return err
 }
 
 // GetBook returns first found book by given filter.
 //
-// Use squirrel for filtering, e.g. postgres.GetBook(ctx, sq.Eq{"id": id}) to get book by id.
-func GetBook(ctx context.Context, filter any) (*Book, error) {
-	query, params, err := psql.Select(bookColumns...).From("books").ToSql()
+// Use squirrel for filtering, e.g. store.GetBook(ctx, sq.Eq{"id": id}) to get book by id.
+func (s *Store) GetBook(ctx context.Context, filter any) (*storage.Book, error) {
+	q := psql.Select(bookColumns...).From("books")
+	if filter != nil {
+		q = q.Where(filter)
+	}
+
+	query, params, err := q.OrderBy("created_at").Limit(1).ToSql()
 	if err != nil {
 		return nil, err
 	}
 
-	var book Book
-	row := getDB(ctx).QueryRow(ctx, query, params...)
-	err = book.scan(row)
+	book, err := scanBook(s.db(ctx).QueryRow(ctx, query, params...))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("cannot get book: %w", storage.ErrNotFound)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("cannot get book: %w", err)
 	}
 
-	return &book, nil
+	return book, nil
 }
 
 // FindBooks returns books by given filter.
 //
-// Use squirrel for filtering, e.g. postgres.FindBooks(ctx, sq.Eq{"published": false}) to get books that aren't published yet.
-func FindBooks(ctx context.Context, filter any) ([]*Book, error) {
+// Use squirrel for filtering, e.g. store.FindBooks(ctx, sq.Eq{"publisher": publisher}) to get books of publisher.
+func (s *Store) FindBooks(ctx context.Context, filter any) ([]*storage.Book, error) {
 	q := psql.Select(bookColumns...).From("books")
 	if filter != nil {
 		q = q.Where(filter)
@@ -153,20 +246,59 @@ func FindBooks(ctx context.Context, filter any) ([]*Book, error) {
 		return nil, err
 	}
 
-	var books []*Book
-	rows, err := getDB(ctx).Query(ctx, query, params...)
+	rows, err := s.db(ctx).Query(ctx, query, params...)
 	if err != nil {
 		return nil, fmt.Errorf("cannot find books: %w", err)
 	}
+	defer rows.Close()
 
+	return scanBooks(rows)
+}
+
+// scanBooks scans all rows selected with bookColumns.
+func scanBooks(rows pgx.Rows) ([]*storage.Book, error) {
+	var books []*storage.Book
 	for rows.Next() {
-		var book Book
-		if err := book.scan(rows); err != nil {
+		book, err := scanBook(rows)
+		if err != nil {
 			return nil, fmt.Errorf("cannot scan book: %w", err)
 		}
 
-		books = append(books, &book)
+		books = append(books, book)
+	}
+
+	return books, rows.Err()
+}
+
+// FindBookKeys returns urls and ISBNs of books updated after given time, all books if it's zero.
+//
+// Scrapers use them to skip books that are already stored and were scraped recently.
+func (s *Store) FindBookKeys(ctx context.Context, updatedAfter time.Time) (urls []string, isbns []string, err error) {
+	q := psql.Select("url", "isbn").From("books")
+	if !updatedAfter.IsZero() {
+		q = q.Where(sq.Gt{"updated_at": updatedAfter.UTC()})
+	}
+
+	query, params, err := q.ToSql()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rows, err := s.db(ctx).Query(ctx, query, params...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("cannot find book keys: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var url, isbn string
+		if err := rows.Scan(&url, &isbn); err != nil {
+			return nil, nil, fmt.Errorf("cannot scan book keys: %w", err)
+		}
+
+		urls = append(urls, url)
+		isbns = append(isbns, isbn)
 	}
 
-	return books, nil
+	return urls, isbns, rows.Err()
 }
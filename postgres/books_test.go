@@ -2,16 +2,18 @@ package postgres
 
 import (
 	"testing"
+	"time"
 
 	sq "github.com/Masterminds/squirrel"
 	"github.com/matryer/is"
+	"github.com/tommsawyer/itbooks/storage"
 )
 
 func TestUpsertBookInsertUnexistingBook(t *testing.T) {
 	ctx, is, rollback := testTransaction(t)
 	defer rollback()
 
-	params := UpsertBookParams{
+	params := storage.UpsertBookParams{
 		ISBN:        "isbn",
 		URL:         "url",
 		Title:       "title",
@@ -22,11 +24,13 @@ func TestUpsertBookInsertUnexistingBook(t *testing.T) {
 		Properties: map[string]string{
 			"test": "test",
 		},
+		ReleaseDate: time.Date(2024, 3, 12, 0, 0, 0, 0, time.UTC),
+		Status:      "preorder",
 	}
-	id, err := UpsertBook(ctx, params)
+	id, err := store.UpsertBook(ctx, params)
 	is.NoErr(err) // we can create book
 
-	book, err := GetBook(ctx, sq.Eq{"id": id})
+	book, err := store.GetBook(ctx, sq.Eq{"id": id})
 	is.NoErr(err) // we should be able to get created book by id
 
 	assertBookFieldsMatch(is, id, book, params)
@@ -36,7 +40,7 @@ func TestUpsertBookWithTheSameISBNUpdatesBook(t *testing.T) {
 	ctx, is, rollback := testTransaction(t)
 	defer rollback()
 
-	params := UpsertBookParams{
+	params := storage.UpsertBookParams{
 		ISBN:        "isbn",
 		URL:         "url",
 		Title:       "title",
@@ -48,10 +52,10 @@ func TestUpsertBookWithTheSameISBNUpdatesBook(t *testing.T) {
 			"test": "test",
 		},
 	}
-	oldID, err := UpsertBook(ctx, params)
+	oldID, err := store.UpsertBook(ctx, params)
 	is.NoErr(err) // we can create book
 
-	updatedParams := UpsertBookParams{
+	updatedParams := storage.UpsertBookParams{
 		ISBN:        "isbn",
 		URL:         "url2",
 		Title:       "title2",
@@ -63,59 +67,117 @@ func TestUpsertBookWithTheSameISBNUpdatesBook(t *testing.T) {
 			"test": "test",
 		},
 	}
-	newID, err := UpsertBook(ctx, updatedParams)
+	newID, err := store.UpsertBook(ctx, updatedParams)
 	is.NoErr(err)
 
 	is.Equal(oldID, newID) // book should be updated, not created
 
-	book, err := GetBook(ctx, sq.Eq{"id": newID})
+	book, err := store.GetBook(ctx, sq.Eq{"id": newID})
 	is.NoErr(err) // we should be able to get updated book by id
 
 	assertBookFieldsMatch(is, newID, book, updatedParams)
 }
 
-func TestFindBooks(t *testing.T) {
+func TestUpsertBookAdoptsBookWithSyntheticISBN(t *testing.T) {
 	ctx, is, rollback := testTransaction(t)
 	defer rollback()
 
-	publishedBook := UpsertBookParams{
-		ISBN:  "isbn",
-		Title: "published",
+	params := storage.UpsertBookParams{
+		ISBN:  "url:dmkpress.com/book",
+		URL:   "https://dmkpress.com/book",
+		Title: "title",
 	}
-	publishedID, err := UpsertBook(ctx, publishedBook)
+	oldID, err := store.UpsertBook(ctx, params)
 	is.NoErr(err)
 
-	is.NoErr(UpdateBook(ctx, publishedID, Fields{
-		"published": true,
-	}))
+	params.ISBN = "9785970609873"
+	newID, err := store.UpsertBook(ctx, params)
+	is.NoErr(err)
+
+	is.Equal(oldID, newID) // book with synthetic isbn should get real isbn
+
+	book, err := store.GetBook(ctx, sq.Eq{"id": newID})
+	is.NoErr(err)
+
+	assertBookFieldsMatch(is, newID, book, params)
+}
+
+func TestFindBooks(t *testing.T) {
+	ctx, is, rollback := testTransaction(t)
+	defer rollback()
+
+	_, err := store.UpsertBook(ctx, storage.UpsertBookParams{
+		ISBN:  "isbn",
+		Title: "title",
+	})
+	is.NoErr(err)
 
-	unpublishedBook := UpsertBookParams{
+	updatedBook := storage.UpsertBookParams{
 		ISBN:  "isbn2",
-		Title: "unpublished",
+		Title: "title",
 	}
-	id, err := UpsertBook(ctx, unpublishedBook)
+	id, err := store.UpsertBook(ctx, updatedBook)
 	is.NoErr(err)
 
-	books, err := FindBooks(ctx, sq.Eq{"published": false})
+	is.NoErr(store.UpdateBook(ctx, id, Fields{
+		"title": "updated",
+	}))
+	updatedBook.Title = "updated"
+
+	books, err := store.FindBooks(ctx, sq.Eq{"title": "updated"})
 	is.NoErr(err)
 
 	is.Equal(len(books), 1)
-	assertBookFieldsMatch(is, id, books[0], unpublishedBook)
+	assertBookFieldsMatch(is, id, books[0], updatedBook)
+}
+
+func TestGetBookUsesFilter(t *testing.T) {
+	ctx, is, rollback := testTransaction(t)
+	defer rollback()
+
+	_, err := store.UpsertBook(ctx, storage.UpsertBookParams{ISBN: "isbn", Title: "first"})
+	is.NoErr(err)
+
+	second := storage.UpsertBookParams{ISBN: "isbn2", Title: "second"}
+	id, err := store.UpsertBook(ctx, second)
+	is.NoErr(err)
+
+	book, err := store.GetBook(ctx, sq.Eq{"isbn": "isbn2"})
+	is.NoErr(err)
+
+	assertBookFieldsMatch(is, id, book, second) // book should be found by isbn, not the first one
+}
+
+func TestFindBookKeys(t *testing.T) {
+	ctx, is, rollback := testTransaction(t)
+	defer rollback()
+
+	_, err := store.UpsertBook(ctx, storage.UpsertBookParams{ISBN: "isbn", URL: "url", Title: "title"})
+	is.NoErr(err) // we can create book
+
+	urls, isbns, err := store.FindBookKeys(ctx, time.Time{})
+	is.NoErr(err) // we can find keys of books
+
+	is.Equal(urls, []string{"url"})
+	is.Equal(isbns, []string{"isbn"})
+
+	urls, _, err = store.FindBookKeys(ctx, time.Now().Add(time.Hour))
+	is.NoErr(err)
+	is.Equal(len(urls), 0) // book wasn't updated recently, so it should be scraped again
 }
 
-func assertBookFieldsMatch(is *is.I, id int64, book *Book, params UpsertBookParams) {
+func assertBookFieldsMatch(is *is.I, id int64, book *storage.Book, params storage.UpsertBookParams) {
 	is.Helper()
 
 	is.Equal(book.ID, id)
-	is.Equal(book.ISBN.String, params.ISBN)
-	is.Equal(book.URL.String, params.URL)
-	is.Equal(book.Title.String, params.Title)
-	is.Equal(book.Image.String, params.Image)
-	is.Equal(book.Description.String, params.Description)
-	is.Equal(len(book.Authors.Elements), len(params.Authors))
-	for i, e := range book.Authors.Elements {
-		is.Equal(e.String, params.Authors[i])
-	}
-	is.Equal(book.Publisher.String, params.Publisher)
+	is.Equal(book.ISBN, params.ISBN)
+	is.Equal(book.URL, params.URL)
+	is.Equal(book.Title, params.Title)
+	is.Equal(book.Image, params.Image)
+	is.Equal(book.Description, params.Description)
+	is.Equal(book.Authors, params.Authors)
+	is.Equal(book.Publisher, params.Publisher)
 	is.Equal(book.Properties, params.Properties)
+	is.True(book.ReleaseDate.Equal(params.ReleaseDate))
+	is.Equal(book.Status, params.Status)
 }
@@ -3,16 +3,16 @@ package postgres
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/Masterminds/squirrel"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/tommsawyer/itbooks/storage"
 )
 
-var (
-	psql = squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar)
-	db   DB
-)
+var psql = squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar)
 
 // Fields is used for update queries.
 type Fields = map[string]any
@@ -25,29 +25,91 @@ type DB interface {
 	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
 }
 
-// Connect connects to postgres.
-func Connect(ctx context.Context, uri string) error {
-	conn, err := pgx.Connect(ctx, uri)
+var _ storage.BookStore = (*Store)(nil)
+
+// Config is configuration of postgres connection pool.
+type Config struct {
+	URI string
+	// MaxConns is maximum size of pool. Default of pgxpool is used if zero.
+	MaxConns int32
+	// HealthCheckPeriod is how often idle connections are checked. Default of pgxpool is used if zero.
+	HealthCheckPeriod time.Duration
+}
+
+// Store is postgres database accessed through connection pool.
+// It's safe for concurrent use.
+type Store struct {
+	pool *pgxpool.Pool
+}
+
+// Open creates connection pool and checks that postgres is reachable.
+// Store should be closed after use.
+func Open(ctx context.Context, config Config) (*Store, error) {
+	poolConfig, err := pgxpool.ParseConfig(config.URI)
 	if err != nil {
-		return fmt.Errorf("failed to connect to postgres: %w", err)
+		return nil, fmt.Errorf("cannot parse postgres uri: %w", err)
+	}
+
+	if config.MaxConns > 0 {
+		poolConfig.MaxConns = config.MaxConns
+	}
+	if config.HealthCheckPeriod > 0 {
+		poolConfig.HealthCheckPeriod = config.HealthCheckPeriod
+	}
+
+	pool, err := pgxpool.NewWithConfig(ctx, poolConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to postgres: %w", err)
+	}
+
+	store := &Store{pool: pool}
+	if err := store.Ping(ctx); err != nil {
+		pool.Close()
+		return nil, err
+	}
+
+	return store, nil
+}
+
+// Close waits for acquired connections to be released and closes all connections.
+func (s *Store) Close() {
+	s.pool.Close()
+}
+
+// Ping checks that postgres is reachable.
+func (s *Store) Ping(ctx context.Context) error {
+	if err := s.pool.Ping(ctx); err != nil {
+		return fmt.Errorf("postgres is unreachable: %w", err)
 	}
-	db = conn
+
 	return nil
 }
 
 type transactionKey struct{}
 
-// getDB returns transaction attached to context
-// or global database connection.
-func getDB(ctx context.Context) DB {
+// inTransaction runs f with transaction attached to context.
+// Transaction is committed if f succeeds and rolled back otherwise.
+// When context already has transaction, savepoint is used instead.
+func (s *Store) inTransaction(ctx context.Context, f func(ctx context.Context) error) error {
+	tx, err := s.db(ctx).Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("cannot begin transaction: %w", err)
+	}
+
+	if err := f(context.WithValue(ctx, transactionKey{}, tx)); err != nil {
+		_ = tx.Rollback(ctx)
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// db returns transaction attached to context or connection pool.
+func (s *Store) db(ctx context.Context) DB {
 	tx, ok := ctx.Value(transactionKey{}).(pgx.Tx)
 	if ok {
 		return tx
 	}
 
-	if db == nil {
-		panic("call postgres.Connect() before using queries")
-	}
-
-	return db
+	return s.pool
 }
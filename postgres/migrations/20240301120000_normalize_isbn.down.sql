@@ -0,0 +1 @@
+-- Original ISBN formatting and duplicate books removed by the up migration are lost, nothing to revert.
@@ -0,0 +1,85 @@
+-- ISBNs are stored as ISBN-13 without hyphens, see isbn.Parse.
+
+-- isbn13_check_digit returns check digit for first 12 digits of ISBN-13.
+CREATE FUNCTION isbn13_check_digit(digits TEXT) RETURNS TEXT
+LANGUAGE plpgsql IMMUTABLE
+AS $$
+DECLARE
+  total INTEGER := 0;
+BEGIN
+  FOR i IN 1..12 LOOP
+    total := total + substr(digits, i, 1)::INTEGER * CASE WHEN i % 2 = 0 THEN 3 ELSE 1 END;
+  END LOOP;
+
+  RETURN ((10 - total % 10) % 10)::TEXT;
+END
+$$;
+
+-- normalize_isbn converts ISBN-10 or ISBN-13 to ISBN-13 without hyphens.
+-- It returns NULL for invalid ISBN, e.g. with wrong check digit.
+CREATE FUNCTION normalize_isbn(raw TEXT) RETURNS TEXT
+LANGUAGE plpgsql IMMUTABLE
+AS $$
+DECLARE
+  digits TEXT := translate(regexp_replace(upper(btrim(raw)), '^ISBN(-1[03])?[: ]*', ''), '- ', '');
+  total INTEGER := 0;
+  check_digit TEXT;
+BEGIN
+  IF digits ~ '^[0-9]{13}$' AND isbn13_check_digit(digits) = right(digits, 1) THEN
+    RETURN digits;
+  END IF;
+
+  IF digits ~ '^[0-9]{9}[0-9X]$' THEN
+    FOR i IN 1..9 LOOP
+      total := total + substr(digits, i, 1)::INTEGER * (11 - i);
+    END LOOP;
+
+    check_digit := CASE (11 - total % 11) % 11 WHEN 10 THEN 'X' ELSE ((11 - total % 11) % 11)::TEXT END;
+    IF check_digit = right(digits, 1) THEN
+      digits := '978' || left(digits, 9);
+      RETURN digits || isbn13_check_digit(digits);
+    END IF;
+  END IF;
+
+  RETURN NULL;
+END
+$$;
+
+-- Books without valid ISBN (e.g. DMK Press url slugs) are stored under synthetic "url:" keys.
+-- They will get real ISBN on the next scrape, see postgres.UpsertBook.
+CREATE TEMPORARY TABLE normalized_isbns AS
+SELECT id, COALESCE(normalize_isbn(isbn), 'url:' || regexp_replace(url, '^https?://', '')) AS isbn, updated_at
+FROM books;
+
+-- The same book could be stored several times with differently formatted ISBNs.
+-- The most recently updated copy is kept, and it stays published if any copy was published,
+-- so the book isn't posted again.
+UPDATE books
+SET published = TRUE
+FROM normalized_isbns n
+WHERE books.id = n.id
+  AND NOT books.published
+  AND EXISTS (
+    SELECT 1
+    FROM normalized_isbns duplicate
+    JOIN books b ON b.id = duplicate.id
+    WHERE duplicate.isbn = n.isbn AND b.published
+  );
+
+DELETE FROM books
+USING normalized_isbns n
+WHERE books.id = n.id
+  AND EXISTS (
+    SELECT 1
+    FROM normalized_isbns newer
+    WHERE newer.isbn = n.isbn AND (newer.updated_at, newer.id) > (n.updated_at, n.id)
+  );
+
+UPDATE books
+SET isbn = n.isbn
+FROM normalized_isbns n
+WHERE books.id = n.id AND books.isbn <> n.isbn;
+
+DROP TABLE normalized_isbns;
+DROP FUNCTION normalize_isbn(TEXT);
+DROP FUNCTION isbn13_check_digit(TEXT);
@@ -0,0 +1,2 @@
+ALTER TABLE books DROP COLUMN status;
+ALTER TABLE books DROP COLUMN release_date;
@@ -0,0 +1,23 @@
+ALTER TABLE books ADD COLUMN release_date DATE;
+ALTER TABLE books ADD COLUMN status TEXT CHECK (status IN ('preorder', 'in_stock', 'out_of_print'));
+
+-- Scrapers used to store release date only in properties.
+-- Dates and years are checked by regex before cast, so one malformed value like 2023-02-30
+-- doesn't fail the whole migration. Like scrapers, year is used when date is invalid.
+WITH scraped AS (
+  SELECT id, substring(properties->>'date_published' for 10) AS date_published, properties->>'year' AS year
+  FROM books
+), release AS (
+  SELECT id, CASE
+    WHEN date_published ~ '^[1-9][0-9]{3}-((0[1-9]|1[0-2])-(0[1-9]|1[0-9]|2[0-8])|(0[13-9]|1[0-2])-(29|30)|(0[13578]|1[02])-31)$'
+      -- leap day
+      OR date_published ~ '^([1-9][0-9](0[48]|[2468][048]|[13579][26])|([2468][048]|[13579][26])00)-02-29$'
+      THEN date_published::date
+    WHEN year ~ '^[1-9][0-9]{3}$' THEN make_date(year::int, 1, 1)
+  END AS release_date
+  FROM scraped
+)
+UPDATE books
+SET release_date = release.release_date
+FROM release
+WHERE books.id = release.id AND release.release_date IS NOT NULL;
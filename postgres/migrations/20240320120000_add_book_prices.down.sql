@@ -0,0 +1,4 @@
+DROP TABLE book_prices;
+
+ALTER TABLE books DROP COLUMN currency;
+ALTER TABLE books DROP COLUMN price;
@@ -0,0 +1,12 @@
+ALTER TABLE books ADD COLUMN price NUMERIC(12, 2);
+ALTER TABLE books ADD COLUMN currency TEXT;
+
+CREATE TABLE book_prices (
+  id SERIAL PRIMARY KEY,
+  book_id INTEGER NOT NULL REFERENCES books(id) ON DELETE CASCADE,
+  price NUMERIC(12, 2) NOT NULL,
+  currency TEXT NOT NULL,
+  created_at timestamp NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX book_prices_book_id_idx ON book_prices (book_id, created_at);
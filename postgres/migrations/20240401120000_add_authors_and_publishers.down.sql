@@ -0,0 +1,5 @@
+ALTER TABLE books DROP COLUMN publisher_id;
+DROP TABLE book_authors;
+DROP TABLE authors;
+DROP TABLE publishers;
+DROP FUNCTION normalize_name(TEXT);
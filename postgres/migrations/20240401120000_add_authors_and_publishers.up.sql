@@ -0,0 +1,62 @@
+-- normalize_name makes key used to match names regardless of case, "ё" and dots after initials,
+-- so "Иванов И." and "иванов и" are the same author.
+CREATE FUNCTION normalize_name(name TEXT) RETURNS TEXT
+LANGUAGE SQL IMMUTABLE STRICT
+AS $$ SELECT btrim(regexp_replace(lower(translate(name, 'Ёё', 'Ее')), '[.[:space:]]+', ' ', 'g')) $$;
+
+CREATE TABLE publishers (
+  id SERIAL PRIMARY KEY,
+  name TEXT NOT NULL,
+  normalized_name TEXT NOT NULL GENERATED ALWAYS AS (normalize_name(name)) STORED UNIQUE
+);
+
+CREATE TABLE authors (
+  id SERIAL PRIMARY KEY,
+  name TEXT NOT NULL,
+  normalized_name TEXT NOT NULL GENERATED ALWAYS AS (normalize_name(name)) STORED UNIQUE
+);
+
+CREATE TABLE book_authors (
+  book_id INTEGER NOT NULL REFERENCES books(id) ON DELETE CASCADE,
+  author_id INTEGER NOT NULL REFERENCES authors(id) ON DELETE CASCADE,
+  position INTEGER NOT NULL,
+  PRIMARY KEY (book_id, author_id)
+);
+
+CREATE INDEX book_authors_author_id_idx ON book_authors (author_id);
+
+ALTER TABLE books ADD COLUMN publisher_id INTEGER REFERENCES publishers(id);
+CREATE INDEX books_publisher_id_idx ON books (publisher_id);
+
+-- Names are cleaned the same way as cleanName and cleanAuthorName do in postgres package.
+INSERT INTO publishers (name)
+SELECT DISTINCT ON (normalize_name(name)) name
+FROM (SELECT btrim(regexp_replace(publisher, '\s+', ' ', 'g')) AS name, created_at FROM books) p
+WHERE name <> ''
+ORDER BY normalize_name(name), created_at;
+
+UPDATE books b
+SET publisher_id = p.id
+FROM publishers p
+WHERE p.normalized_name = normalize_name(btrim(regexp_replace(b.publisher, '\s+', ' ', 'g')));
+
+CREATE TEMPORARY TABLE book_author_names AS
+SELECT b.id AS book_id, b.created_at, a.position, btrim(regexp_replace(
+  regexp_replace(a.name, '[[:space:],;]*(^|[[:space:],;])([Пп]еревод|[Пп]ер\.|[Tt]ranslated by)([[:space:]:].*)?$', ''),
+  '\s+', ' ', 'g'
+)) AS name
+FROM books b, unnest(b.authors) WITH ORDINALITY AS a(name, position);
+
+INSERT INTO authors (name)
+SELECT DISTINCT ON (normalize_name(name)) name
+FROM book_author_names
+WHERE name <> ''
+ORDER BY normalize_name(name), created_at, position;
+
+INSERT INTO book_authors (book_id, author_id, position)
+SELECT n.book_id, a.id, min(n.position)
+FROM book_author_names n
+JOIN authors a ON a.normalized_name = normalize_name(n.name)
+GROUP BY n.book_id, a.id;
+
+DROP TABLE book_author_names;
@@ -0,0 +1,2 @@
+ALTER TABLE books DROP COLUMN search_vector;
+DROP FUNCTION book_search_vector(TEXT, TEXT[], TEXT);
@@ -0,0 +1,19 @@
+-- book_search_vector makes document for full-text search over book in both russian and english,
+-- so words are found regardless of language morphology. Title is ranked higher than authors and description.
+CREATE FUNCTION book_search_vector(title TEXT, authors TEXT[], description TEXT) RETURNS tsvector
+LANGUAGE SQL STABLE
+AS $$
+  SELECT
+    setweight(to_tsvector('russian', coalesce(title, '')), 'A') ||
+    setweight(to_tsvector('english', coalesce(title, '')), 'A') ||
+    setweight(to_tsvector('russian', coalesce(array_to_string(authors, ' '), '')), 'B') ||
+    setweight(to_tsvector('english', coalesce(array_to_string(authors, ' '), '')), 'B') ||
+    setweight(to_tsvector('russian', coalesce(description, '')), 'C') ||
+    setweight(to_tsvector('english', coalesce(description, '')), 'C')
+$$;
+
+ALTER TABLE books ADD COLUMN search_vector tsvector;
+
+UPDATE books SET search_vector = book_search_vector(title, authors, description);
+
+CREATE INDEX books_search_vector_idx ON books USING GIN (search_vector);
@@ -0,0 +1,12 @@
+-- Revisions store changes of book fields made by scrapers, one row per field.
+-- Revisions of one upsert share created_at, which is time of its transaction.
+CREATE TABLE book_revisions (
+  id SERIAL PRIMARY KEY,
+  book_id INTEGER NOT NULL REFERENCES books(id) ON DELETE CASCADE,
+  field TEXT NOT NULL,
+  old_value TEXT NOT NULL,
+  new_value TEXT NOT NULL,
+  created_at timestamp NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX book_revisions_book_id_idx ON book_revisions (book_id, created_at);
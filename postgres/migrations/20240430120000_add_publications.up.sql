@@ -0,0 +1,19 @@
+-- Publications record every attempt to announce book to channel of target, e.g. telegram.
+CREATE TABLE publications (
+  id SERIAL PRIMARY KEY,
+  book_id INTEGER NOT NULL REFERENCES books(id) ON DELETE CASCADE,
+  target TEXT NOT NULL,
+  channel TEXT NOT NULL,
+  message_id TEXT,
+  status TEXT NOT NULL CHECK (status IN ('published', 'failed')),
+  published_at timestamp NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX publications_book_id_idx ON publications (book_id, target, channel);
+
+-- Books used to be published to the only telegram channel, which isn't known here.
+-- Publications with empty channel count as published to every channel of target.
+INSERT INTO publications (book_id, target, channel, status, published_at)
+SELECT id, 'telegram', '', 'published', updated_at FROM books WHERE published;
+
+ALTER TABLE books DROP COLUMN published;
@@ -0,0 +1,3 @@
+-- edited_at is time of the last edit of published message, NULL if it wasn't edited.
+-- Book changes made after it are not reflected in the message yet.
+ALTER TABLE publications ADD COLUMN edited_at timestamp;
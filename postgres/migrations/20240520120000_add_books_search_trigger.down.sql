@@ -0,0 +1,2 @@
+DROP TRIGGER books_search_vector_update ON books;
+DROP FUNCTION update_book_search_vector();
@@ -0,0 +1,17 @@
+-- search_vector is maintained by trigger, so book stays searchable
+-- whichever query changes its title, authors or description.
+CREATE FUNCTION update_book_search_vector() RETURNS trigger
+LANGUAGE plpgsql
+AS $$
+BEGIN
+  NEW.search_vector := book_search_vector(NEW.title, NEW.authors, NEW.description);
+  RETURN NEW;
+END
+$$;
+
+CREATE TRIGGER books_search_vector_update
+BEFORE INSERT OR UPDATE OF title, authors, description ON books
+FOR EACH ROW EXECUTE FUNCTION update_book_search_vector();
+
+-- Books edited by UpdateBook kept search vector of their old fields.
+UPDATE books SET search_vector = book_search_vector(title, authors, description);
@@ -0,0 +1 @@
+ALTER TABLE publications DROP COLUMN with_image;
@@ -0,0 +1,4 @@
+-- with_image is set if message was sent with image, so its caption is edited instead of text.
+-- Messages published before it was tracked are assumed to have image,
+-- editing caption of text message falls back to editing text, see telegram.EditCaption.
+ALTER TABLE publications ADD COLUMN with_image BOOLEAN NOT NULL DEFAULT TRUE;
@@ -0,0 +1,135 @@
+package migrations
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+
+	"github.com/golang-migrate/migrate/v4"
+	_ "github.com/golang-migrate/migrate/v4/database/postgres" // postgres:// database driver
+	"github.com/golang-migrate/migrate/v4/source"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+)
+
+// ErrOutdated is returned by Check when database schema isn't up to date.
+var ErrOutdated = errors.New("database schema is outdated")
+
+// Migrator applies embedded migrations to postgres.
+type Migrator struct {
+	migrate *migrate.Migrate
+	source  source.Driver
+}
+
+// New creates migrator for postgres with given uri.
+func New(uri string) (*Migrator, error) {
+	src, err := iofs.New(Migrations, ".")
+	if err != nil {
+		return nil, fmt.Errorf("cannot read migrations: %w", err)
+	}
+
+	m, err := migrate.NewWithSourceInstance("iofs", src, uri)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create migrator: %w", err)
+	}
+
+	return &Migrator{migrate: m, source: src}, nil
+}
+
+// SetLogger logs applied migrations to logger.
+func (m *Migrator) SetLogger(logger *log.Logger) {
+	m.migrate.Log = &migrateLogger{logger}
+}
+
+// Up applies all migrations that aren't applied yet.
+func (m *Migrator) Up() error {
+	return ignoreNoChange(m.migrate.Up())
+}
+
+// Down rolls back given number of applied migrations.
+func (m *Migrator) Down(steps int) error {
+	return ignoreNoChange(m.migrate.Steps(-steps))
+}
+
+// Force sets schema version without running migrations and clears dirty state.
+// It is used to recover after migration failed halfway.
+func (m *Migrator) Force(version int) error {
+	return m.migrate.Force(version)
+}
+
+// Version returns current schema version, zero if no migrations are applied.
+// Dirty is true when the last migration failed.
+func (m *Migrator) Version() (version uint, dirty bool, err error) {
+	version, dirty, err = m.migrate.Version()
+	if errors.Is(err, migrate.ErrNilVersion) {
+		return 0, false, nil
+	}
+
+	return version, dirty, err
+}
+
+// Latest returns version of the newest embedded migration.
+func (m *Migrator) Latest() (uint, error) {
+	version, err := m.source.First()
+	if err != nil {
+		return 0, fmt.Errorf("cannot read first migration: %w", err)
+	}
+
+	for {
+		next, err := m.source.Next(version)
+		if errors.Is(err, fs.ErrNotExist) {
+			return version, nil
+		}
+		if err != nil {
+			return 0, fmt.Errorf("cannot read migration after %d: %w", version, err)
+		}
+
+		version = next
+	}
+}
+
+// Check returns ErrOutdated unless all embedded migrations are applied successfully.
+func (m *Migrator) Check() error {
+	version, dirty, err := m.Version()
+	if err != nil {
+		return err
+	}
+
+	latest, err := m.Latest()
+	if err != nil {
+		return err
+	}
+
+	if dirty {
+		return fmt.Errorf("%w: migration %d failed", ErrOutdated, version)
+	}
+	if version != latest {
+		return fmt.Errorf("%w: version is %d, latest is %d", ErrOutdated, version, latest)
+	}
+
+	return nil
+}
+
+// Close closes connection to database.
+func (m *Migrator) Close() error {
+	sourceErr, dbErr := m.migrate.Close()
+	if sourceErr != nil {
+		return sourceErr
+	}
+
+	return dbErr
+}
+
+func ignoreNoChange(err error) error {
+	if errors.Is(err, migrate.ErrNoChange) {
+		return nil
+	}
+
+	return err
+}
+
+type migrateLogger struct {
+	*log.Logger
+}
+
+func (*migrateLogger) Verbose() bool { return false }
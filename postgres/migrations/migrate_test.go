@@ -0,0 +1,41 @@
+package migrations_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/matryer/is"
+	"github.com/tommsawyer/itbooks/postgres/migrations"
+	"github.com/tommsawyer/itbooks/postgres/postgrestest"
+)
+
+func TestMigratorRollsBackAndAppliesMigrations(t *testing.T) {
+	is := is.New(t)
+
+	uri, stopPostgres, err := postgrestest.RunContainer(context.Background(), testing.Verbose())
+	is.NoErr(err) // testing postgres should run with all migrations applied
+	defer stopPostgres()
+
+	m, err := migrations.New(uri)
+	is.NoErr(err)
+	defer m.Close()
+
+	is.NoErr(m.Check()) // schema should be up to date
+
+	latest, err := m.Latest()
+	is.NoErr(err)
+
+	version, dirty, err := m.Version()
+	is.NoErr(err)
+	is.Equal(version, latest)
+	is.True(!dirty)
+
+	is.NoErr(m.Down(1))                                   // last migration should be rolled back
+	is.True(errors.Is(m.Check(), migrations.ErrOutdated)) // schema should be outdated
+
+	is.NoErr(m.Up())
+	is.NoErr(m.Check()) // schema should be up to date again
+
+	is.NoErr(m.Up()) // applying no migrations is not an error
+}
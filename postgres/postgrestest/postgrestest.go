@@ -2,16 +2,12 @@ package postgrestest
 
 import (
 	"context"
-	"errors"
 	"fmt"
 	"io"
 	"log"
 	"os"
 	"testing"
 
-	"github.com/golang-migrate/migrate/v4"
-	_ "github.com/golang-migrate/migrate/v4/database/postgres"
-	"github.com/golang-migrate/migrate/v4/source/iofs"
 	_ "github.com/jackc/pgx/v5/stdlib"
 	"github.com/testcontainers/testcontainers-go"
 	"github.com/testcontainers/testcontainers-go/wait"
@@ -60,37 +56,17 @@ func RunContainer(ctx context.Context, verbose bool) (uri string, cleanup func()
 }
 
 func applyMigrations(postgresURI string) error {
-	d, err := iofs.New(migrations.Migrations, ".")
-	if err != nil {
-		return err
-	}
-
-	m, err := migrate.NewWithSourceInstance("iofs", d, postgresURI)
+	m, err := migrations.New(postgresURI)
 	if err != nil {
 		return err
 	}
 	if testing.Verbose() {
-		m.Log = &migrateLogger{log.New(os.Stdout, "", log.LstdFlags)}
+		m.SetLogger(log.New(os.Stdout, "", log.LstdFlags))
 	}
 
-	err = m.Up()
-	if errors.Is(err, migrate.ErrNoChange) {
-		return nil
-	}
-	if err != nil {
+	if err := m.Up(); err != nil {
 		return err
 	}
 
-	sourceErr, dbErr := m.Close()
-	if sourceErr != nil {
-		return sourceErr
-	}
-
-	return dbErr
+	return m.Close()
 }
-
-type migrateLogger struct {
-	*log.Logger
-}
-
-func (*migrateLogger) Verbose() bool { return false }
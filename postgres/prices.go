@@ -0,0 +1,58 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/tommsawyer/itbooks/storage"
+)
+
+// addBookPrice adds price to history of book prices.
+func (s *Store) addBookPrice(ctx context.Context, bookID int64, price float64, currency string) error {
+	query, args, err := psql.Insert("book_prices").
+		Columns("book_id", "price", "currency").
+		Values(bookID, price, currency).
+		ToSql()
+	if err != nil {
+		return err
+	}
+
+	if _, err := s.db(ctx).Exec(ctx, query, args...); err != nil {
+		return fmt.Errorf("cannot add book price: %w", err)
+	}
+
+	return nil
+}
+
+// FindBookPrices returns price history of book with given ISBN, from the oldest price to the newest one.
+func (s *Store) FindBookPrices(ctx context.Context, isbn string) ([]*storage.BookPrice, error) {
+	query, args, err := psql.
+		Select("p.id", "p.book_id", "p.price", "p.currency", "p.created_at").
+		From("book_prices p").
+		Join("books b ON b.id = p.book_id").
+		Where(sq.Eq{"b.isbn": isbn}).
+		OrderBy("p.created_at", "p.id").
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db(ctx).Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("cannot find book prices: %w", err)
+	}
+	defer rows.Close()
+
+	var prices []*storage.BookPrice
+	for rows.Next() {
+		var price storage.BookPrice
+		if err := rows.Scan(&price.ID, &price.BookID, &price.Price, &price.Currency, &price.CreatedAt); err != nil {
+			return nil, fmt.Errorf("cannot scan book price: %w", err)
+		}
+
+		prices = append(prices, &price)
+	}
+
+	return prices, rows.Err()
+}
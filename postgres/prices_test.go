@@ -0,0 +1,39 @@
+package postgres
+
+import (
+	"testing"
+
+	"github.com/tommsawyer/itbooks/storage"
+)
+
+func TestUpsertBookAddsPriceToHistory(t *testing.T) {
+	ctx, is, rollback := testTransaction(t)
+	defer rollback()
+
+	params := storage.UpsertBookParams{
+		ISBN:     "isbn",
+		URL:      "url",
+		Title:    "title",
+		Price:    1299,
+		Currency: "RUB",
+	}
+	id, err := store.UpsertBook(ctx, params)
+	is.NoErr(err)
+
+	params.Price = 1499.5
+	_, err = store.UpsertBook(ctx, params)
+	is.NoErr(err)
+
+	params.Price = 0 // unknown price is not stored
+	_, err = store.UpsertBook(ctx, params)
+	is.NoErr(err)
+
+	prices, err := store.FindBookPrices(ctx, "isbn")
+	is.NoErr(err)
+
+	is.Equal(len(prices), 2)
+	is.Equal(prices[0].BookID, id)
+	is.Equal(prices[0].Price, 1299.0)
+	is.Equal(prices[0].Currency, "RUB")
+	is.Equal(prices[1].Price, 1499.5)
+}
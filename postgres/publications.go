@@ -0,0 +1,199 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/tommsawyer/itbooks/storage"
+)
+
+// OutdatedPublication is publication of book changed after it was published or edited last time.
+type OutdatedPublication struct {
+	storage.Publication
+	// ImageChanged is set if cover of book changed, so image of message should be replaced too.
+	ImageChanged bool
+}
+
+var publicationColumns = []string{
+	"id",
+	"book_id",
+	"target",
+	"channel",
+	"message_id",
+	"with_image",
+	"status",
+	"published_at",
+	"edited_at",
+}
+
+// publicationRow is row of publications table, nullable columns are scanned into pgtype values.
+type publicationRow struct {
+	storage.Publication
+	messageID pgtype.Text
+	editedAt  pgtype.Timestamp
+}
+
+// fields returns pointers to fields in order of publicationColumns.
+func (r *publicationRow) fields() []any {
+	p := &r.Publication
+	return []any{&p.ID, &p.BookID, &p.Target, &p.Channel, &r.messageID, &p.WithImage, &p.Status, &p.PublishedAt, &r.editedAt}
+}
+
+// publication returns scanned publication.
+func (r *publicationRow) publication() storage.Publication {
+	p := r.Publication
+	p.MessageID = r.messageID.String
+	p.EditedAt = r.editedAt.Time
+	return p
+}
+
+// unpublished is filter of books that aren't published to channel of target
+// and didn't fail to be published there storage.MaxFailedPublications times.
+//
+// Books published before publications were introduced have publications
+// with empty channel, which count as published to every channel of target.
+func unpublished(target, channel string) sq.Sqlizer {
+	return sq.And{
+		sq.Expr(
+			"NOT EXISTS (SELECT 1 FROM publications p WHERE p.book_id = books.id AND p.target = ? AND p.channel IN (?, '') AND p.status = ?)",
+			target, channel, storage.PublicationPublished,
+		),
+		sq.Expr(
+			"(SELECT COUNT(*) FROM publications p WHERE p.book_id = books.id AND p.target = ? AND p.channel = ? AND p.status = ?) < ?",
+			target, channel, storage.PublicationFailed, storage.MaxFailedPublications,
+		),
+	}
+}
+
+// AddPublication records publication of book and returns its ID.
+func (s *Store) AddPublication(ctx context.Context, params storage.AddPublicationParams) (int64, error) {
+	query, args, err := psql.Insert("publications").
+		Columns("book_id", "target", "channel", "message_id", "with_image", "status").
+		Values(
+			params.BookID, params.Target, params.Channel,
+			pgtype.Text{String: params.MessageID, Valid: params.MessageID != ""},
+			params.WithImage,
+			params.Status,
+		).
+		Suffix("RETURNING id").
+		ToSql()
+	if err != nil {
+		return 0, err
+	}
+
+	var id int64
+	if err := s.db(ctx).QueryRow(ctx, query, args...).Scan(&id); err != nil {
+		return 0, fmt.Errorf("cannot add publication: %w", err)
+	}
+
+	return id, nil
+}
+
+// FindUnpublishedBooks returns books that aren't published to channel of target yet.
+// Books that failed to be published storage.MaxFailedPublications times are skipped.
+func (s *Store) FindUnpublishedBooks(ctx context.Context, target, channel string) ([]*storage.Book, error) {
+	return s.FindBooks(ctx, unpublished(target, channel))
+}
+
+// FindPublications returns publications of book, from the oldest to the newest.
+func (s *Store) FindPublications(ctx context.Context, bookID int64) ([]*storage.Publication, error) {
+	query, args, err := psql.
+		Select(publicationColumns...).
+		From("publications").
+		Where(sq.Eq{"book_id": bookID}).
+		OrderBy("published_at", "id").
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db(ctx).Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("cannot find publications: %w", err)
+	}
+	defer rows.Close()
+
+	var publications []*storage.Publication
+	for rows.Next() {
+		var row publicationRow
+		if err := rows.Scan(row.fields()...); err != nil {
+			return nil, fmt.Errorf("cannot scan publication: %w", err)
+		}
+
+		p := row.publication()
+		publications = append(publications, &p)
+	}
+
+	return publications, rows.Err()
+}
+
+// lastSync is time when message of publication p reflected book for the last time.
+const lastSync = "COALESCE(p.edited_at, p.published_at)"
+
+// FindOutdatedPublications returns messages published to channel of target that don't reflect book anymore,
+// because its title, cover, description or price changed since publication or the last edit.
+// Publications without message id can't be edited, so they are skipped.
+func (s *Store) FindOutdatedPublications(ctx context.Context, target, channel string) ([]*OutdatedPublication, error) {
+	columns := make([]string, 0, len(publicationColumns)+1)
+	for _, column := range publicationColumns {
+		columns = append(columns, "p."+column)
+	}
+
+	query, args, err := psql.
+		Select(columns...).
+		Column("EXISTS (SELECT 1 FROM book_revisions r WHERE r.book_id = p.book_id AND r.field = 'image' AND r.created_at > "+lastSync+")").
+		From("publications p").
+		Join("books b ON b.id = p.book_id").
+		Where(sq.Eq{"p.target": target, "p.channel": channel, "p.status": storage.PublicationPublished}).
+		Where(sq.NotEq{"p.message_id": nil}).
+		Where(sq.Or{
+			sq.Expr("EXISTS (SELECT 1 FROM book_revisions r WHERE r.book_id = p.book_id AND r.field IN ('title', 'image', 'description') AND r.created_at > " + lastSync + ")"),
+			// price history has row for every scrape, so current price is compared with price known at last sync
+			sq.Expr("b.price IS DISTINCT FROM (SELECT bp.price FROM book_prices bp WHERE bp.book_id = p.book_id AND bp.created_at <= " + lastSync + " ORDER BY bp.created_at DESC, bp.id DESC LIMIT 1)"),
+		}).
+		OrderBy("p.published_at", "p.id").
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db(ctx).Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("cannot find outdated publications: %w", err)
+	}
+	defer rows.Close()
+
+	var publications []*OutdatedPublication
+	for rows.Next() {
+		var (
+			row          publicationRow
+			imageChanged bool
+		)
+		if err := rows.Scan(append(row.fields(), &imageChanged)...); err != nil {
+			return nil, fmt.Errorf("cannot scan publication: %w", err)
+		}
+
+		publications = append(publications, &OutdatedPublication{Publication: row.publication(), ImageChanged: imageChanged})
+	}
+
+	return publications, rows.Err()
+}
+
+// MarkPublicationEdited records that message of publication was edited to reflect current book.
+func (s *Store) MarkPublicationEdited(ctx context.Context, id int64) error {
+	query, args, err := psql.Update("publications").
+		Set("edited_at", sq.Expr("NOW()")).
+		Where(sq.Eq{"id": id}).
+		ToSql()
+	if err != nil {
+		return err
+	}
+
+	if _, err := s.db(ctx).Exec(ctx, query, args...); err != nil {
+		return fmt.Errorf("cannot mark publication as edited: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,178 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+
+	"github.com/matryer/is"
+	"github.com/tommsawyer/itbooks/storage"
+)
+
+func TestFindUnpublishedBooks(t *testing.T) {
+	ctx, is, rollback := testTransaction(t)
+	defer rollback()
+
+	publishedID, err := store.UpsertBook(ctx, storage.UpsertBookParams{ISBN: "isbn", Title: "published"})
+	is.NoErr(err)
+
+	_, err = store.AddPublication(ctx, storage.AddPublicationParams{
+		BookID:    publishedID,
+		Target:    "telegram",
+		Channel:   "@first",
+		MessageID: "42",
+		WithImage: true,
+		Status:    storage.PublicationPublished,
+	})
+	is.NoErr(err)
+
+	failedID, err := store.UpsertBook(ctx, storage.UpsertBookParams{ISBN: "isbn2", Title: "failed"})
+	is.NoErr(err)
+
+	_, err = store.AddPublication(ctx, storage.AddPublicationParams{
+		BookID:  failedID,
+		Target:  "telegram",
+		Channel: "@first",
+		Status:  storage.PublicationFailed,
+	})
+	is.NoErr(err)
+
+	books, err := store.FindUnpublishedBooks(ctx, "telegram", "@first")
+	is.NoErr(err)
+	is.Equal(len(books), 1) // failed publication should be retried
+	is.Equal(books[0].ID, failedID)
+
+	books, err = store.FindUnpublishedBooks(ctx, "telegram", "@second")
+	is.NoErr(err)
+	is.Equal(len(books), 2) // books published to another channel are unpublished
+
+	publications, err := store.FindPublications(ctx, publishedID)
+	is.NoErr(err)
+	is.Equal(len(publications), 1)
+	is.Equal(publications[0].Target, "telegram")
+	is.Equal(publications[0].Channel, "@first")
+	is.Equal(publications[0].MessageID, "42")
+	is.True(publications[0].WithImage)
+	is.Equal(publications[0].Status, storage.PublicationPublished)
+	is.True(!publications[0].PublishedAt.IsZero())
+}
+
+func TestFindUnpublishedBooksSkipsBooksPublishedToEveryChannel(t *testing.T) {
+	ctx, is, rollback := testTransaction(t)
+	defer rollback()
+
+	id, err := store.UpsertBook(ctx, storage.UpsertBookParams{ISBN: "isbn", Title: "title"})
+	is.NoErr(err)
+
+	// publications migrated from published flag have empty channel
+	_, err = store.AddPublication(ctx, storage.AddPublicationParams{
+		BookID: id,
+		Target: "telegram",
+		Status: storage.PublicationPublished,
+	})
+	is.NoErr(err)
+
+	books, err := store.FindUnpublishedBooks(ctx, "telegram", "@channel")
+	is.NoErr(err)
+	is.Equal(len(books), 0)
+}
+
+// backdate moves publications, prices and revisions to the past.
+// Test runs in single transaction, so otherwise all of them would have the same time.
+func backdate(ctx context.Context, is *is.I) {
+	for _, query := range []string{
+		"UPDATE publications SET published_at = published_at - interval '1 hour', edited_at = edited_at - interval '1 hour'",
+		"UPDATE book_prices SET created_at = created_at - interval '1 hour'",
+		"UPDATE book_revisions SET created_at = created_at - interval '1 hour'",
+	} {
+		_, err := store.db(ctx).Exec(ctx, query)
+		is.NoErr(err)
+	}
+}
+
+func TestFindOutdatedPublications(t *testing.T) {
+	ctx, is, rollback := testTransaction(t)
+	defer rollback()
+
+	params := storage.UpsertBookParams{ISBN: "isbn", Title: "title", Image: "image", Price: 100, Currency: "RUB"}
+	id, err := store.UpsertBook(ctx, params)
+	is.NoErr(err)
+
+	publicationID, err := store.AddPublication(ctx, storage.AddPublicationParams{
+		BookID:    id,
+		Target:    "telegram",
+		Channel:   "@channel",
+		MessageID: "42",
+		Status:    storage.PublicationPublished,
+	})
+	is.NoErr(err)
+	backdate(ctx, is)
+
+	// the same book scraped again
+	_, err = store.UpsertBook(ctx, params)
+	is.NoErr(err)
+
+	publications, err := store.FindOutdatedPublications(ctx, "telegram", "@channel")
+	is.NoErr(err)
+	is.Equal(len(publications), 0) // message reflects book
+
+	params.Price = 200
+	_, err = store.UpsertBook(ctx, params)
+	is.NoErr(err)
+
+	publications, err = store.FindOutdatedPublications(ctx, "telegram", "@channel")
+	is.NoErr(err)
+	is.Equal(len(publications), 1) // price changed
+	is.Equal(publications[0].ID, publicationID)
+	is.Equal(publications[0].MessageID, "42")
+	is.True(!publications[0].ImageChanged)
+
+	is.NoErr(store.MarkPublicationEdited(ctx, publicationID))
+
+	publications, err = store.FindOutdatedPublications(ctx, "telegram", "@channel")
+	is.NoErr(err)
+	is.Equal(len(publications), 0) // message was edited
+	backdate(ctx, is)
+
+	params.Image = "new image"
+	_, err = store.UpsertBook(ctx, params)
+	is.NoErr(err)
+
+	publications, err = store.FindOutdatedPublications(ctx, "telegram", "@channel")
+	is.NoErr(err)
+	is.Equal(len(publications), 1)
+	is.True(publications[0].ImageChanged) // cover changed after edit
+
+	publications, err = store.FindOutdatedPublications(ctx, "telegram", "@another")
+	is.NoErr(err)
+	is.Equal(len(publications), 0) // book isn't published to another channel
+}
+
+func TestFindUnpublishedBooksSkipsBooksFailedTooManyTimes(t *testing.T) {
+	ctx, is, rollback := testTransaction(t)
+	defer rollback()
+
+	id, err := store.UpsertBook(ctx, storage.UpsertBookParams{ISBN: "isbn", Title: "title"})
+	is.NoErr(err)
+
+	for i := 0; i < storage.MaxFailedPublications; i++ {
+		books, err := store.FindUnpublishedBooks(ctx, "telegram", "@channel")
+		is.NoErr(err)
+		is.Equal(len(books), 1) // book should be retried
+
+		_, err = store.AddPublication(ctx, storage.AddPublicationParams{
+			BookID:  id,
+			Target:  "telegram",
+			Channel: "@channel",
+			Status:  storage.PublicationFailed,
+		})
+		is.NoErr(err)
+	}
+
+	books, err := store.FindUnpublishedBooks(ctx, "telegram", "@channel")
+	is.NoErr(err)
+	is.Equal(len(books), 0)
+
+	books, err = store.FindUnpublishedBooks(ctx, "telegram", "@another")
+	is.NoErr(err)
+	is.Equal(len(books), 1) // failures in another channel don't count
+}
@@ -0,0 +1,139 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/tommsawyer/itbooks/storage"
+)
+
+// BookRevision represents book_revisions table in postgres.
+// It's a change of one field of book noticed while upserting it.
+type BookRevision struct {
+	ID        int64            `db:"id"`
+	BookID    int64            `db:"book_id"`
+	Field     string           `db:"field"`
+	OldValue  string           `db:"old_value"`
+	NewValue  string           `db:"new_value"`
+	CreatedAt pgtype.Timestamp `db:"created_at"`
+}
+
+// bookChange is change of book field.
+type bookChange struct {
+	field    string
+	old, new string
+}
+
+// bookChanges returns fields of stored book changed by params.
+// Price has its own history and properties are too noisy, so they aren't compared.
+func bookChanges(book *storage.Book, params storage.UpsertBookParams) []bookChange {
+	var oldReleaseDate, newReleaseDate string
+	if !book.ReleaseDate.IsZero() {
+		oldReleaseDate = book.ReleaseDate.Format("2006-01-02")
+	}
+	if !params.ReleaseDate.IsZero() {
+		newReleaseDate = params.ReleaseDate.Format("2006-01-02")
+	}
+
+	fields := []bookChange{
+		{"url", book.URL, params.URL},
+		{"title", book.Title, params.Title},
+		{"image", book.Image, params.Image},
+		{"description", book.Description, params.Description},
+		{"authors", strings.Join(book.Authors, ", "), strings.Join(params.Authors, ", ")},
+		{"publisher", book.Publisher, params.Publisher},
+		{"release_date", oldReleaseDate, newReleaseDate},
+		{"status", book.Status, params.Status},
+	}
+
+	var changes []bookChange
+	for _, field := range fields {
+		if field.old != field.new {
+			changes = append(changes, field)
+		}
+	}
+
+	return changes
+}
+
+// lockBook returns book with given ISBN locked until the end of transaction or nil if it doesn't exist.
+func (s *Store) lockBook(ctx context.Context, isbn string) (*storage.Book, error) {
+	query, args, err := psql.Select(bookColumns...).
+		From("books").
+		Where(sq.Eq{"isbn": isbn}).
+		Suffix("FOR UPDATE").
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	book, err := scanBook(s.db(ctx).QueryRow(ctx, query, args...))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot lock book: %w", err)
+	}
+
+	return book, nil
+}
+
+// addBookRevisions records changes of book.
+func (s *Store) addBookRevisions(ctx context.Context, bookID int64, changes []bookChange) error {
+	if len(changes) == 0 {
+		return nil
+	}
+
+	q := psql.Insert("book_revisions").Columns("book_id", "field", "old_value", "new_value")
+	for _, change := range changes {
+		q = q.Values(bookID, change.field, change.old, change.new)
+	}
+
+	query, args, err := q.ToSql()
+	if err != nil {
+		return err
+	}
+
+	if _, err := s.db(ctx).Exec(ctx, query, args...); err != nil {
+		return fmt.Errorf("cannot add book revisions: %w", err)
+	}
+
+	return nil
+}
+
+// FindBookRevisions returns changes of book with given ISBN, from the oldest to the newest.
+func (s *Store) FindBookRevisions(ctx context.Context, isbn string) ([]*BookRevision, error) {
+	query, args, err := psql.
+		Select("r.id", "r.book_id", "r.field", "r.old_value", "r.new_value", "r.created_at").
+		From("book_revisions r").
+		Join("books b ON b.id = r.book_id").
+		Where(sq.Eq{"b.isbn": isbn}).
+		OrderBy("r.created_at", "r.id").
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db(ctx).Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("cannot find book revisions: %w", err)
+	}
+	defer rows.Close()
+
+	var revisions []*BookRevision
+	for rows.Next() {
+		var r BookRevision
+		if err := rows.Scan(&r.ID, &r.BookID, &r.Field, &r.OldValue, &r.NewValue, &r.CreatedAt); err != nil {
+			return nil, fmt.Errorf("cannot scan book revision: %w", err)
+		}
+
+		revisions = append(revisions, &r)
+	}
+
+	return revisions, rows.Err()
+}
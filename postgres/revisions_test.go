@@ -0,0 +1,71 @@
+package postgres
+
+import (
+	"testing"
+	"time"
+
+	"github.com/matryer/is"
+	"github.com/tommsawyer/itbooks/storage"
+)
+
+func TestBookChanges(t *testing.T) {
+	is := is.New(t)
+
+	book := &storage.Book{
+		URL:         "url",
+		Title:       "Чистый кд",
+		Image:       "image",
+		Description: "description",
+		Authors:     []string{"Мартин Р."},
+		Publisher:   "Питер",
+	}
+
+	changes := bookChanges(book, storage.UpsertBookParams{
+		URL:         "url",
+		Title:       "Чистый код",
+		Image:       "image2",
+		Description: "description",
+		Authors:     []string{"Мартин Р."},
+		Publisher:   "Питер",
+		ReleaseDate: time.Date(2024, 3, 12, 0, 0, 0, 0, time.UTC),
+	})
+
+	is.Equal(changes, []bookChange{
+		{field: "title", old: "Чистый кд", new: "Чистый код"},
+		{field: "image", old: "image", new: "image2"},
+		{field: "release_date", old: "", new: "2024-03-12"},
+	})
+}
+
+func TestUpsertBookRecordsRevisions(t *testing.T) {
+	ctx, is, rollback := testTransaction(t)
+	defer rollback()
+
+	params := storage.UpsertBookParams{
+		ISBN:  "isbn",
+		URL:   "url",
+		Title: "Чистый кд",
+		Image: "image",
+	}
+	id, err := store.UpsertBook(ctx, params)
+	is.NoErr(err)
+
+	_, err = store.UpsertBook(ctx, params)
+	is.NoErr(err) // nothing changed
+
+	params.Title = "Чистый код"
+	params.Image = "image2"
+	_, err = store.UpsertBook(ctx, params)
+	is.NoErr(err)
+
+	revisions, err := store.FindBookRevisions(ctx, "isbn")
+	is.NoErr(err)
+
+	is.Equal(len(revisions), 2)
+	is.Equal(revisions[0].BookID, id)
+	is.Equal(revisions[0].Field, "title")
+	is.Equal(revisions[0].OldValue, "Чистый кд")
+	is.Equal(revisions[0].NewValue, "Чистый код")
+	is.Equal(revisions[1].Field, "image")
+	is.Equal(revisions[1].NewValue, "image2")
+}
@@ -0,0 +1,37 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/tommsawyer/itbooks/storage"
+)
+
+// SearchBooks returns up to limit books matching query, the most relevant first.
+//
+// Query is parsed like web search input, e.g. `kubernetes -docker` or `"чистый код"`,
+// with both russian and english morphology, so "книги" matches "книга".
+// Title matches are ranked higher than authors and description ones.
+func (s *Store) SearchBooks(ctx context.Context, query string, limit int) ([]*storage.Book, error) {
+	q := psql.Select(bookColumns...).
+		From("books").
+		CrossJoin("(SELECT websearch_to_tsquery('russian', ?) || websearch_to_tsquery('english', ?) AS q) search_query", query, query).
+		Where("search_vector @@ search_query.q").
+		OrderBy("ts_rank(search_vector, search_query.q) DESC", "created_at DESC")
+	if limit > 0 {
+		q = q.Limit(uint64(limit))
+	}
+
+	statement, args, err := q.ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db(ctx).Query(ctx, statement, args...)
+	if err != nil {
+		return nil, fmt.Errorf("cannot search books: %w", err)
+	}
+	defer rows.Close()
+
+	return scanBooks(rows)
+}
@@ -0,0 +1,80 @@
+package postgres
+
+import (
+	"testing"
+
+	"github.com/tommsawyer/itbooks/storage"
+)
+
+func TestSearchBooks(t *testing.T) {
+	ctx, is, rollback := testTransaction(t)
+	defer rollback()
+
+	kubernetesID, err := store.UpsertBook(ctx, storage.UpsertBookParams{
+		ISBN:        "isbn",
+		Title:       "Kubernetes в действии",
+		Authors:     []string{"Лукша М."},
+		Description: "Книга о развертывании приложений.",
+	})
+	is.NoErr(err)
+
+	mentionID, err := store.UpsertBook(ctx, storage.UpsertBookParams{
+		ISBN:        "isbn2",
+		Title:       "Docker для профессионалов",
+		Description: "Контейнеры и их запуск в Kubernetes.",
+	})
+	is.NoErr(err)
+
+	_, err = store.UpsertBook(ctx, storage.UpsertBookParams{
+		ISBN:  "isbn3",
+		Title: "Чистый код",
+	})
+	is.NoErr(err)
+
+	books, err := store.SearchBooks(ctx, "kubernetes", 10)
+	is.NoErr(err)
+	is.Equal(len(books), 2)
+	is.Equal(books[0].ID, kubernetesID) // title match should be ranked higher
+	is.Equal(books[1].ID, mentionID)
+
+	books, err = store.SearchBooks(ctx, "книги о приложении", 10)
+	is.NoErr(err)
+	is.Equal(len(books), 1) // russian words should match other forms
+	is.Equal(books[0].ID, kubernetesID)
+
+	books, err = store.SearchBooks(ctx, "лукша", 10)
+	is.NoErr(err)
+	is.Equal(len(books), 1) // authors should be searched
+	is.Equal(books[0].ID, kubernetesID)
+
+	books, err = store.SearchBooks(ctx, "kubernetes", 1)
+	is.NoErr(err)
+	is.Equal(len(books), 1) // limit should be respected
+}
+
+func TestSearchBooksAfterUpdate(t *testing.T) {
+	ctx, is, rollback := testTransaction(t)
+	defer rollback()
+
+	params := storage.UpsertBookParams{ISBN: "isbn", Title: "Go на практике"}
+	id, err := store.UpsertBook(ctx, params)
+	is.NoErr(err)
+
+	params.Title = "Rust на практике"
+	_, err = store.UpsertBook(ctx, params)
+	is.NoErr(err)
+
+	books, err := store.SearchBooks(ctx, "rust", 10)
+	is.NoErr(err)
+	is.Equal(len(books), 1) // search should use updated title
+
+	books, err = store.SearchBooks(ctx, "go", 10)
+	is.NoErr(err)
+	is.Equal(len(books), 0)
+
+	is.NoErr(store.UpdateBook(ctx, id, Fields{"description": "Книга о микросервисах."}))
+
+	books, err = store.SearchBooks(ctx, "микросервисы", 10)
+	is.NoErr(err)
+	is.Equal(len(books), 1) // fields changed outside of upsert should be searched too
+}
@@ -0,0 +1,191 @@
+package scraper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/gocolly/colly"
+)
+
+// transport is used by every collector to make http requests.
+// Tests replace it to serve recorded pages from testdata instead of live sites.
+var transport http.RoundTripper = http.DefaultTransport
+
+// Config controls how politely sites are crawled.
+type Config struct {
+	// UserAgent is sent with every request and used to match robots.txt rules.
+	UserAgent string
+	// Parallelism is maximum number of concurrent requests of one domain.
+	Parallelism int
+	// Delay between requests of one domain.
+	Delay time.Duration
+	// Retries is number of retries of requests failed with 5xx status, 429 status or timeout.
+	Retries int
+	// RetryDelay is delay before the first retry, it doubles with every next one.
+	RetryDelay time.Duration
+}
+
+// DefaultConfig is used by collectors unless Configure is called.
+var DefaultConfig = Config{
+	UserAgent:   "itbooks (+https://github.com/tommsawyer/itbooks)",
+	Parallelism: 2,
+	Delay:       500 * time.Millisecond,
+	Retries:     3,
+	RetryDelay:  time.Second,
+}
+
+var config = DefaultConfig
+
+// Configure sets config of collectors.
+//
+// It's not safe to call Configure while scraping.
+func Configure(c Config) {
+	config = c
+}
+
+// newCollector creates collector that should be used by all sites.
+//
+// Collector is asynchronous, so sites should start scraping with visit.
+// Requests are bound to ctx: in-flight ones are aborted and new ones
+// are not started once ctx is done. Transient errors are retried,
+// robots.txt of every site is respected.
+// Visited pages and http errors are counted in site report attached to ctx.
+func newCollector(ctx context.Context) *colly.Collector {
+	collector := colly.NewCollector(
+		colly.Async(true),
+		colly.UserAgent(config.UserAgent),
+	)
+	collector.IgnoreRobotsTxt = false
+	collector.WithTransport(&contextTransport{ctx: ctx, base: transport})
+
+	limits := &domainLimits{collector: collector, limited: make(map[string]bool)}
+	collector.OnRequest(func(r *colly.Request) {
+		if ctx.Err() != nil {
+			r.Abort()
+			return
+		}
+
+		limits.limit(r.URL.Host)
+	})
+
+	report := reportFromContext(ctx)
+
+	collector.OnResponse(func(r *colly.Response) {
+		if report != nil {
+			report.pageVisited()
+		}
+	})
+
+	collector.OnError(func(r *colly.Response, err error) {
+		if retry(ctx, r, err) {
+			return
+		}
+
+		if report != nil {
+			report.httpFailed()
+			log.Printf("[%s] cannot get %s: %v", report.Name, r.Request.URL, err)
+		}
+	})
+
+	return collector
+}
+
+// domainLimits adds limit rule for every domain visited by collector,
+// so each domain gets its own parallelism and delay.
+// Rule matching all domains would make them share one limit.
+type domainLimits struct {
+	collector *colly.Collector
+
+	mu      sync.Mutex
+	limited map[string]bool
+}
+
+// limit adds rule for domain unless it's added already.
+// Rules are matched when request is sent, so it should be called before that, e.g. in OnRequest.
+func (l *domainLimits) limit(domain string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.limited[domain] {
+		return
+	}
+	l.limited[domain] = true
+
+	// quoted domain is always valid regexp
+	_ = l.collector.Limit(&colly.LimitRule{
+		DomainRegexp: "^" + regexp.QuoteMeta(domain) + "$",
+		Parallelism:  config.Parallelism,
+		Delay:        config.Delay,
+	})
+}
+
+// visit starts scraping from url and waits until all pages are visited.
+// It returns error of ctx if scraping was aborted, since requests are aborted silently.
+func visit(ctx context.Context, collector *colly.Collector, url string) error {
+	if err := collector.Visit(url); err != nil {
+		return err
+	}
+
+	collector.Wait()
+
+	return ctx.Err()
+}
+
+// retry retries request failed with transient error with exponential backoff.
+// It reports whether request was retried.
+func retry(ctx context.Context, r *colly.Response, err error) bool {
+	if ctx.Err() != nil || !transient(r, err) {
+		return false
+	}
+
+	// request context is shared with requests made from its callbacks,
+	// so retries are counted per url
+	key := fmt.Sprintf("retries %s", r.Request.URL)
+	retries, _ := r.Ctx.GetAny(key).(int)
+	if retries >= config.Retries {
+		return false
+	}
+	r.Ctx.Put(key, retries+1)
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-time.After(config.RetryDelay << retries):
+	}
+
+	if err := r.Request.Retry(); err != nil {
+		return false
+	}
+
+	return true
+}
+
+// transient reports whether request failed with error that may disappear on retry.
+func transient(r *colly.Response, err error) bool {
+	if r.StatusCode >= http.StatusInternalServerError || r.StatusCode == http.StatusTooManyRequests {
+		return true
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// contextTransport binds every request to ctx.
+//
+// colly doesn't support contexts, so this is the only way
+// to cancel requests that are already in flight.
+type contextTransport struct {
+	ctx  context.Context
+	base http.RoundTripper
+}
+
+func (t *contextTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return t.base.RoundTrip(req.WithContext(t.ctx))
+}
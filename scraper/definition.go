@@ -0,0 +1,317 @@
+package scraper
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"strings"
+	"sync/atomic"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/gocolly/colly"
+	"github.com/tommsawyer/itbooks/isbn"
+)
+
+// Definition describes site that can be scraped without writing any Go code.
+//
+// Scraping starts from StartURL, follows Links to book pages and Pagination to next list pages.
+// Every element matched by Book selector on visited pages is parsed into book using Fields.
+//
+// Sites that publish RSS or Atom feed of new books need only Feed url, see Feed.
+type Definition struct {
+	Name      string `json:"name"`
+	Publisher string `json:"publisher"`
+	// Feed is url of RSS or Atom feed. Page selectors are ignored when it's set.
+	Feed string `json:"feed"`
+	// Language of books, stored in book details. Used only with feeds.
+	Language   string     `json:"language"`
+	StartURL   string     `json:"start_url"`
+	Links      string     `json:"links"`
+	Pagination Pagination `json:"pagination"`
+	Book       string     `json:"book"`
+	Fields     Fields     `json:"fields"`
+}
+
+// Pagination describes how to find next list pages.
+type Pagination struct {
+	Selector string `json:"selector"`
+	// MaxPages limits number of visited list pages, including start page.
+	// Zero means no limit.
+	MaxPages int `json:"max_pages"`
+}
+
+// Fields describes how to extract book fields from book element.
+type Fields struct {
+	// ISBN is searched in the whole book element text when no rule is set.
+	ISBN        Field `json:"isbn"`
+	Title       Field `json:"title"`
+	Authors     Field `json:"authors"`
+	Image       Field `json:"image"`
+	Description Field `json:"description"`
+	// ReleaseDate is parsed from formats like "2024-03-12", "12.03.2024" or "2024".
+	ReleaseDate Field `json:"release_date"`
+	// Status is parsed from phrases like "Предзаказ", "В наличии" or schema.org availability.
+	Status Field `json:"status"`
+	// Price is parsed from text like "1 299 ₽", currency is detected by its symbol.
+	Price   Field            `json:"price"`
+	Details map[string]Field `json:"details"`
+}
+
+// Field is rule to extract one value from book element.
+type Field struct {
+	// Selector of element with value. Book element itself is used if empty.
+	Selector string `json:"selector"`
+	// Attr is attribute with value. Element text is used if empty.
+	Attr string `json:"attr"`
+	// Regexp is applied to value, first capturing group (or whole match) is used.
+	Regexp string `json:"regexp"`
+	// Split separates value into list, e.g. authors. Defaults to ",".
+	Split string `json:"split"`
+	// Document makes selector search the whole page instead of book element.
+	Document bool `json:"document"`
+
+	regexp *regexp.Regexp
+}
+
+// LoadDefinitions reads JSON array of definitions from file
+// and registers them as sites. Definitions override built-in sites with the same name.
+func LoadDefinitions(path string) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("cannot read site definitions: %w", err)
+	}
+
+	defined, err := parseDefinitions(content)
+	if err != nil {
+		return err
+	}
+
+	for name, site := range defined {
+		Register(name, site)
+	}
+
+	return nil
+}
+
+// parseDefinitions parses JSON array of definitions into sites by name.
+func parseDefinitions(content []byte) (map[string]Site, error) {
+	var definitions []Definition
+	if err := json.Unmarshal(content, &definitions); err != nil {
+		return nil, fmt.Errorf("cannot parse site definitions: %w", err)
+	}
+
+	defined := make(map[string]Site, len(definitions))
+	for _, definition := range definitions {
+		site, err := definition.Site()
+		if err != nil {
+			return nil, err
+		}
+
+		defined[definition.Name] = site
+	}
+
+	return defined, nil
+}
+
+// Register adds site that can be scraped by name.
+// It replaces already registered site with the same name.
+//
+// It's not safe to call Register while scraping.
+func Register(name string, site Site) {
+	sites[name] = site
+}
+
+// Site validates definition and compiles it into Site.
+func (d Definition) Site() (Site, error) {
+	switch {
+	case d.Name == "":
+		return nil, errors.New("site definition has no name")
+	case d.Feed != "":
+		return &Feed{URL: d.Feed, Publisher: d.Publisher, Language: d.Language}, nil
+	case d.StartURL == "":
+		return nil, fmt.Errorf("site %q has no start_url", d.Name)
+	case d.Book == "":
+		return nil, fmt.Errorf("site %q has no book selector", d.Name)
+	}
+
+	for _, field := range []*Field{&d.Fields.ISBN, &d.Fields.Title, &d.Fields.Authors, &d.Fields.Image, &d.Fields.Description, &d.Fields.ReleaseDate, &d.Fields.Status, &d.Fields.Price} {
+		if err := field.compile(); err != nil {
+			return nil, fmt.Errorf("site %q: %w", d.Name, err)
+		}
+	}
+
+	// copy details, so compiled definition doesn't share map with the original one
+	details := make(map[string]Field, len(d.Fields.Details))
+	for name, field := range d.Fields.Details {
+		if err := field.compile(); err != nil {
+			return nil, fmt.Errorf("site %q: %w", d.Name, err)
+		}
+		details[name] = field
+	}
+	d.Fields.Details = details
+
+	return &definedSite{definition: d}, nil
+}
+
+// definedSite is site scraped according to definition.
+type definedSite struct {
+	definition Definition
+}
+
+func (s *definedSite) Scrape(ctx context.Context, books chan<- Book) error {
+	d := s.definition
+	collector := newCollector(ctx)
+
+	if d.Links != "" {
+		collector.OnHTML(d.Links, func(h *colly.HTMLElement) {
+			if err := visitBook(ctx, h, h.Attr("href")); err != nil && !errors.Is(err, colly.ErrAlreadyVisited) {
+				log.Printf("[%s] cannot visit book page: %v", d.Name, err)
+			}
+		})
+	}
+
+	if d.Pagination.Selector != "" {
+		var pages int64 = 1 // start page
+		collector.OnHTML(d.Pagination.Selector, func(h *colly.HTMLElement) {
+			// page is counted before visiting, because next pages
+			// are visited concurrently with this one
+			if d.Pagination.MaxPages > 0 && atomic.AddInt64(&pages, 1) > int64(d.Pagination.MaxPages) {
+				atomic.AddInt64(&pages, -1)
+				return
+			}
+
+			err := visitNextPage(ctx, h, h.Attr("href"))
+			if errors.Is(err, colly.ErrAlreadyVisited) {
+				atomic.AddInt64(&pages, -1)
+				return
+			}
+			if err != nil {
+				log.Printf("[%s] cannot visit pagination page: %v", d.Name, err)
+			}
+		})
+	}
+
+	collector.OnHTML(d.Book, func(h *colly.HTMLElement) {
+		books <- s.parse(h)
+	})
+
+	collector.OnRequest(func(r *colly.Request) {
+		log.Printf("[%s] parsing %s", d.Name, r.URL)
+	})
+
+	return visit(ctx, collector, d.StartURL)
+}
+
+func (s *definedSite) parse(h *colly.HTMLElement) Book {
+	fields := s.definition.Fields
+
+	book := Book{
+		URL:         h.Request.URL.String(),
+		ISBN:        fields.ISBN.extract(h),
+		Title:       fields.Title.extract(h),
+		Authors:     fields.Authors.extractList(h),
+		Description: fields.Description.extract(h),
+		Status:      parseStatus(fields.Status.extract(h)),
+		Publisher:   s.definition.Publisher,
+	}
+	book.ReleaseDate, _ = parseReleaseDate(fields.ReleaseDate.extract(h))
+	setPrice(&book, fields.Price.extract(h), "")
+
+	if image := fields.Image.extract(h); image != "" {
+		book.ImageURL = h.Request.AbsoluteURL(image)
+	}
+
+	if fields.ISBN == (Field{}) {
+		book.ISBN, _ = isbn.Find(h.Text)
+	}
+
+	if len(fields.Details) > 0 {
+		book.Details = make(map[string]string, len(fields.Details))
+		for name, field := range fields.Details {
+			book.Details[name] = field.extract(h)
+		}
+	}
+
+	fillFromMetadata(h, &book)
+
+	return book
+}
+
+func (f *Field) compile() error {
+	if f.Regexp == "" {
+		return nil
+	}
+
+	re, err := regexp.Compile(f.Regexp)
+	if err != nil {
+		return fmt.Errorf("invalid regexp %q: %w", f.Regexp, err)
+	}
+	f.regexp = re
+
+	return nil
+}
+
+func (f *Field) extract(h *colly.HTMLElement) string {
+	if *f == (Field{}) {
+		return ""
+	}
+
+	selection := h.DOM
+	if f.Document {
+		selection = document(selection)
+	}
+	if f.Selector != "" {
+		selection = selection.Find(f.Selector)
+	}
+
+	value := strings.TrimSpace(selection.Text())
+	if f.Attr != "" {
+		value = strings.TrimSpace(selection.First().AttrOr(f.Attr, ""))
+	}
+
+	if f.regexp != nil {
+		match := f.regexp.FindStringSubmatch(value)
+		switch len(match) {
+		case 0:
+			value = ""
+		case 1:
+			value = match[0]
+		default:
+			value = strings.TrimSpace(match[1])
+		}
+	}
+
+	return value
+}
+
+func (f *Field) extractList(h *colly.HTMLElement) []string {
+	value := f.extract(h)
+	if value == "" {
+		return nil
+	}
+
+	separator := f.Split
+	if separator == "" {
+		separator = ","
+	}
+
+	values := strings.Split(value, separator)
+	for i := range values {
+		values[i] = strings.TrimSpace(values[i])
+	}
+
+	return values
+}
+
+// document returns root of the page containing selection.
+func document(selection *goquery.Selection) *goquery.Selection {
+	if parents := selection.Parents(); parents.Length() > 0 {
+		return parents.Last()
+	}
+
+	return selection
+}
@@ -0,0 +1,117 @@
+package scraper
+
+import (
+	"context"
+	"testing"
+
+	"github.com/matryer/is"
+)
+
+func TestDefinedSiteScrapesLikeBuiltInSite(t *testing.T) {
+	is := is.New(t)
+	defer useFixtures("piter")()
+
+	is.NoErr(LoadDefinitions("testdata/definitions.json"))
+	defer delete(sites, "piter-definition")
+	defer delete(sites, "piter-first-page")
+	defer delete(sites, "nostarch-definition")
+
+	books := collectBooks(Scrape(context.Background(), "piter-definition"))
+
+	is.Equal(books, sortBooks(recordedBooksOf("piter")))
+}
+
+func TestDefinedSiteRespectsMaxPages(t *testing.T) {
+	is := is.New(t)
+	defer useFixtures("piter")()
+
+	is.NoErr(LoadDefinitions("testdata/definitions.json"))
+	defer delete(sites, "piter-definition")
+	defer delete(sites, "piter-first-page")
+	defer delete(sites, "nostarch-definition")
+
+	books := collectBooks(Scrape(context.Background(), "piter-first-page"))
+
+	is.Equal(len(books), 2) // only books from the first page should be scraped
+	is.Equal(books[0].Title, "Грокаем алгоритмы. 2-е изд.")
+	is.Equal(books[1].Title, "Kubernetes в действии")
+}
+
+func TestFeedDefinitionScrapesLikeBuiltInFeed(t *testing.T) {
+	is := is.New(t)
+	defer useFixtures("nostarch")()
+
+	is.NoErr(LoadDefinitions("testdata/definitions.json"))
+	defer delete(sites, "piter-definition")
+	defer delete(sites, "piter-first-page")
+	defer delete(sites, "nostarch-definition")
+
+	books := collectBooks(Scrape(context.Background(), "nostarch-definition"))
+
+	is.Equal(books, sortBooks(recordedBooksOf("nostarch")))
+}
+
+func TestDefinitionValidation(t *testing.T) {
+	tests := []struct {
+		name       string
+		definition Definition
+		err        string
+	}{
+		{
+			name:       "no name",
+			definition: Definition{StartURL: "https://example.com", Book: ".book"},
+			err:        "site definition has no name",
+		},
+		{
+			name:       "no start url",
+			definition: Definition{Name: "example", Book: ".book"},
+			err:        `site "example" has no start_url`,
+		},
+		{
+			name:       "no book selector",
+			definition: Definition{Name: "example", StartURL: "https://example.com"},
+			err:        `site "example" has no book selector`,
+		},
+		{
+			name: "invalid regexp",
+			definition: Definition{
+				Name:     "example",
+				StartURL: "https://example.com",
+				Book:     ".book",
+				Fields: Fields{
+					Details: map[string]Field{"year": {Regexp: "("}},
+				},
+			},
+			err: `site "example": invalid regexp "(": error parsing regexp: missing closing ): ` + "`(`",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			is := is.New(t)
+
+			_, err := tt.definition.Site()
+
+			is.Equal(err.Error(), tt.err)
+		})
+	}
+}
+
+func recordedBooksOf(site string) []Book {
+	for _, recorded := range recordedBooks {
+		if recorded.site == site {
+			return recorded.books
+		}
+	}
+
+	return nil
+}
+
+func collectBooks(ch <-chan Book, _ *Report) []Book {
+	var books []Book
+	for book := range ch {
+		books = append(books, book)
+	}
+
+	return sortBooks(books)
+}
@@ -4,35 +4,41 @@ import (
 	"context"
 	"errors"
 	"log"
-	"path"
 	"regexp"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gocolly/colly"
+	"github.com/tommsawyer/itbooks/isbn"
 )
 
+// dmkPressMaxPages is number of the last visited listing page.
+const dmkPressMaxPages = 5
+
 func scrapeDMKPress(ctx context.Context, books chan<- Book) error {
 	const startPage = "https://dmkpress.com/catalog/computer/?&filter%5Bavailable%5D=on&filter%5Bprice%5D%5Bfrom%5D=1&filter%5Bprice%5D%5Bto%5D=2999&filter%5Brelease_date%5D%5Bfrom%5D=1041379200&filter%5Brelease_date%5D%5Bto%5D=1767225600&filter%5Btranslator%5D=&filter%5Bformat%5D=&filter%5Bbumaga%5D=&filter%5Boblozhka%5D=&order_filter%5Brelease_date%5D=1"
-	collector := colly.NewCollector()
+	collector := newCollector(ctx)
 
 	authorRegExp := regexp.MustCompile("(?is)Автор:\n(?P<Author>.*?)Дата выхода")
+	releaseRegExp := regexp.MustCompile(`Дата выхода:\s*([\d.]+)`)
 
 	// links on book list
 	collector.OnHTML("#new-products .item-name a", func(h *colly.HTMLElement) {
-		if err := h.Request.Visit(h.Attr("href")); err != nil && !errors.Is(err, colly.ErrAlreadyVisited) {
+		if err := visitBook(ctx, h, h.Attr("href")); err != nil && !errors.Is(err, colly.ErrAlreadyVisited) {
 			log.Printf("[dmkpress.com] cannot visit book page: %v", err)
 		}
 	})
 
-	// pagination
+	// pagination links every page directly, so stopping at known listing
+	// doesn't prevent visiting the rest of them
 	collector.OnHTML(".pages.pull-right a", func(h *colly.HTMLElement) {
 		page, err := strconv.Atoi(h.Text)
 		if err != nil {
 			return
 		}
 		// we don't need too old books
-		if page > 5 {
+		if page > dmkPressMaxPages {
 			return
 		}
 
@@ -40,7 +46,7 @@ func scrapeDMKPress(ctx context.Context, books chan<- Book) error {
 			return
 		}
 
-		if err := h.Request.Visit(h.Attr("href")); err != nil && !errors.Is(err, colly.ErrAlreadyVisited) {
+		if err := visitNextPage(ctx, h, h.Attr("href")); err != nil && !errors.Is(err, colly.ErrAlreadyVisited) {
 			log.Printf("[dmkpress.com] cannot visit pagination page: %v", err)
 		}
 	})
@@ -63,20 +69,33 @@ func scrapeDMKPress(ctx context.Context, books chan<- Book) error {
 			}
 		}
 
-		books <- Book{
-			ISBN:        path.Base(h.Request.URL.String()),
+		var releaseDate time.Time
+		if match := releaseRegExp.FindStringSubmatch(h.Text); len(match) > 0 {
+			releaseDate, _ = parseReleaseDate(strings.TrimSuffix(match[1], "."))
+		}
+
+		// isbn is not marked up on the page, so it's searched in the whole text
+		bookISBN, _ := isbn.Find(h.Text)
+
+		book := Book{
+			ISBN:        bookISBN,
 			URL:         h.Request.URL.String(),
 			Title:       h.ChildText("span[itemprop=name]"),
 			ImageURL:    h.Request.AbsoluteURL(h.ChildAttr(".card-img", "src")),
 			Description: h.ChildText("#description"),
 			Authors:     authors,
+			ReleaseDate: releaseDate,
 			Publisher:   "ДМК-Пресс",
 		}
+		setPrice(&book, h.ChildText(".price"), "")
+		fillFromMetadata(h, &book)
+
+		books <- book
 	})
 
 	collector.OnRequest(func(r *colly.Request) {
 		log.Println("[dmkpress.com] parsing " + r.URL.String())
 	})
 
-	return collector.Visit(startPage)
+	return visit(ctx, collector, startPage)
 }
@@ -11,11 +11,11 @@ import (
 
 func scrapeEksmo(ctx context.Context, books chan<- Book) error {
 	const startPage = "https://eksmo.ru/professionalnaia-literatura/kompyuternaya-literatura/"
-	collector := colly.NewCollector()
+	collector := newCollector(ctx)
 
 	// book links on list page
 	collector.OnHTML(".book_fast-view[data-last-category=\"Компьютерная литература\"] .book__link", func(h *colly.HTMLElement) {
-		if err := h.Request.Visit(h.Attr("href")); err != nil && !errors.Is(err, colly.ErrAlreadyVisited) {
+		if err := visitBook(ctx, h, h.Attr("href")); err != nil && !errors.Is(err, colly.ErrAlreadyVisited) {
 			log.Printf("[eksmo.com] cannot visit book link on products page: %v", err)
 		}
 	})
@@ -23,7 +23,7 @@ func scrapeEksmo(ctx context.Context, books chan<- Book) error {
 	// pagination elements
 	// obtaining only newest books
 	collector.OnHTML(".pagenav__list a", func(h *colly.HTMLElement) {
-		if err := h.Request.Visit(h.Attr("href")); err != nil && !errors.Is(err, colly.ErrAlreadyVisited) {
+		if err := visitNextPage(ctx, h, h.Attr("href")); err != nil && !errors.Is(err, colly.ErrAlreadyVisited) {
 			log.Printf("[eksmo.com] cannot visit pagination link: %v", err)
 		}
 	})
@@ -42,7 +42,7 @@ func scrapeEksmo(ctx context.Context, books chan<- Book) error {
 			year = publishDate[len(publishDate)-1]
 		}
 
-		books <- Book{
+		book := Book{
 			ISBN:        h.ChildText(".book-page__copy-isbn .copy__val"),
 			URL:         h.Request.URL.String(),
 			Title:       h.ChildText(".book-page__card-title"),
@@ -54,11 +54,15 @@ func scrapeEksmo(ctx context.Context, books chan<- Book) error {
 			},
 			Publisher: "Эксмо",
 		}
+		setPrice(&book, h.ChildText(".book-page__card-price"), "")
+		fillFromMetadata(h, &book)
+
+		books <- book
 	})
 
 	collector.OnRequest(func(r *colly.Request) {
 		log.Println("[eksmo.com] parsing " + r.URL.String())
 	})
 
-	return collector.Visit(startPage)
+	return visit(ctx, collector, startPage)
 }
@@ -0,0 +1,249 @@
+package scraper
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/gocolly/colly"
+	"golang.org/x/net/html/charset"
+
+	"github.com/tommsawyer/itbooks/isbn"
+)
+
+// Feed is site that publishes new books as RSS or Atom feed.
+//
+// Every feed entry is parsed into book. ISBN is searched in entry guid,
+// identifiers and link, books without it get synthetic key.
+// Entries of known books are skipped, see WithKnown.
+type Feed struct {
+	URL       string
+	Publisher string
+	// Language of books in feed, stored in book details if not empty.
+	Language string
+}
+
+// Scrape implements Site.
+func (f *Feed) Scrape(ctx context.Context, books chan<- Book) error {
+	collector := newCollector(ctx)
+
+	var parseErr error
+	collector.OnResponse(func(r *colly.Response) {
+		items, err := parseFeed(r.Body)
+		if err != nil {
+			parseErr = fmt.Errorf("cannot parse feed %s: %w", f.URL, err)
+			return
+		}
+
+		known, report := knownFromContext(ctx), reportFromContext(ctx)
+		for _, item := range items {
+			book := f.book(item)
+			if known != nil && (known.KnownURL(book.URL) || known.KnownISBN(book.ISBN)) {
+				if report != nil {
+					report.bookKnown()
+				}
+				continue
+			}
+
+			books <- book
+		}
+	})
+
+	collector.OnRequest(func(r *colly.Request) {
+		log.Printf("[%s] parsing %s", f.Publisher, r.URL)
+	})
+
+	if err := visit(ctx, collector, f.URL); err != nil {
+		return err
+	}
+
+	return parseErr
+}
+
+func (f *Feed) book(item feedItem) Book {
+	book := Book{
+		URL:         item.link(),
+		Title:       strings.TrimSpace(item.Title),
+		Authors:     item.authors(),
+		ImageURL:    item.image(),
+		Description: item.description(),
+		Publisher:   f.Publisher,
+		Details:     map[string]string{},
+	}
+
+	for _, candidate := range append([]string{item.GUID, item.ID, book.URL}, item.Identifiers...) {
+		if found, ok := isbn.Find(candidate); ok {
+			book.ISBN = found
+			break
+		}
+	}
+
+	if published := item.published(); published != "" {
+		book.Details["date_published"] = published
+	}
+	if f.Language != "" {
+		book.Details["language"] = f.Language
+	}
+
+	return book
+}
+
+// feedDocument is RSS 2.0, RSS 1.0 (RDF) or Atom document.
+type feedDocument struct {
+	ChannelItems []feedItem `xml:"channel>item"`
+	Items        []feedItem `xml:"item"`
+	Entries      []feedItem `xml:"entry"`
+}
+
+// feedItem is RSS item or Atom entry.
+type feedItem struct {
+	Title        string          `xml:"title"`
+	Links        []feedLink      `xml:"link"`
+	GUID         string          `xml:"guid"`
+	ID           string          `xml:"id"`
+	Description  string          `xml:"description"`
+	Summary      string          `xml:"summary"`
+	Content      string          `xml:"http://www.w3.org/2005/Atom content"`
+	Encoded      string          `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
+	Identifiers  []string        `xml:"http://purl.org/dc/elements/1.1/ identifier"`
+	Creators     []string        `xml:"http://purl.org/dc/elements/1.1/ creator"`
+	Authors      []feedAuthor    `xml:"author"`
+	PubDate      string          `xml:"pubDate"`
+	Published    string          `xml:"published"`
+	Updated      string          `xml:"updated"`
+	Date         string          `xml:"http://purl.org/dc/elements/1.1/ date"`
+	Enclosures   []feedEnclosure `xml:"enclosure"`
+	MediaContent []feedEnclosure `xml:"http://search.yahoo.com/mrss/ content"`
+	Thumbnails   []feedEnclosure `xml:"http://search.yahoo.com/mrss/ thumbnail"`
+}
+
+// feedLink is RSS link with url as text or Atom link with url in href.
+type feedLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr"`
+	Text string `xml:",chardata"`
+}
+
+// feedAuthor is Atom author with name or RSS author as text.
+type feedAuthor struct {
+	Name string `xml:"name"`
+	Text string `xml:",chardata"`
+}
+
+type feedEnclosure struct {
+	URL    string `xml:"url,attr"`
+	Type   string `xml:"type,attr"`
+	Medium string `xml:"medium,attr"`
+}
+
+func parseFeed(body []byte) ([]feedItem, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(body))
+	decoder.CharsetReader = charset.NewReaderLabel
+	decoder.Strict = false
+
+	var doc feedDocument
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, err
+	}
+
+	items := append(doc.ChannelItems, doc.Items...)
+	return append(items, doc.Entries...), nil
+}
+
+func (i *feedItem) link() string {
+	for _, link := range i.Links {
+		if link.Rel != "" && link.Rel != "alternate" {
+			continue
+		}
+		if link.Href != "" {
+			return strings.TrimSpace(link.Href)
+		}
+		if text := strings.TrimSpace(link.Text); text != "" {
+			return text
+		}
+	}
+
+	return ""
+}
+
+func (i *feedItem) authors() []string {
+	var authors []string
+	for _, creator := range i.Creators {
+		authors = append(authors, splitAuthors(creator)...)
+	}
+
+	for _, author := range i.Authors {
+		name := author.Name
+		if name == "" {
+			name = author.Text
+		}
+		// RSS author is usually "email (Name)"
+		if start, end := strings.Index(name, "("), strings.LastIndex(name, ")"); start >= 0 && end > start {
+			name = name[start+1 : end]
+		}
+		authors = append(authors, splitAuthors(name)...)
+	}
+
+	return authors
+}
+
+func splitAuthors(s string) []string {
+	var authors []string
+	for _, author := range strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == ';' }) {
+		for _, author := range strings.Split(author, " and ") {
+			if author = strings.TrimSpace(author); author != "" {
+				authors = append(authors, author)
+			}
+		}
+	}
+
+	return authors
+}
+
+func (i *feedItem) image() string {
+	for _, media := range append(i.MediaContent, i.Thumbnails...) {
+		if media.Medium == "image" || strings.HasPrefix(media.Type, "image/") || media.Medium == "" && media.Type == "" {
+			return media.URL
+		}
+	}
+
+	for _, enclosure := range i.Enclosures {
+		if strings.HasPrefix(enclosure.Type, "image/") {
+			return enclosure.URL
+		}
+	}
+
+	return ""
+}
+
+// description returns entry description without html markup.
+func (i *feedItem) description() string {
+	for _, description := range []string{i.Description, i.Summary, i.Content, i.Encoded} {
+		if strings.TrimSpace(description) == "" {
+			continue
+		}
+
+		doc, err := goquery.NewDocumentFromReader(strings.NewReader(description))
+		if err != nil {
+			return strings.TrimSpace(description)
+		}
+
+		return strings.TrimSpace(doc.Text())
+	}
+
+	return ""
+}
+
+func (i *feedItem) published() string {
+	for _, date := range []string{i.PubDate, i.Published, i.Date, i.Updated} {
+		if date = strings.TrimSpace(date); date != "" {
+			return date
+		}
+	}
+
+	return ""
+}
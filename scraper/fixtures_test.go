@@ -0,0 +1,148 @@
+package scraper
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var record = flag.Bool("record", false, "record http fixtures from live sites into testdata")
+
+// fixtureTransport serves http responses from files in dir.
+//
+// When record is true it makes real requests and saves response bodies
+// into dir, so fixtures can be refreshed with `go test ./scraper -record`.
+type fixtureTransport struct {
+	dir    string
+	record bool
+}
+
+func (t *fixtureTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	name := filepath.Join(t.dir, fixtureName(req.URL))
+	if t.record {
+		return t.recordFixture(req, name)
+	}
+
+	files, err := filepath.Glob(name + ".*")
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return fixtureResponse(req, http.StatusNotFound, "text/plain", nil), nil
+	}
+
+	body, err := os.ReadFile(files[0])
+	if err != nil {
+		return nil, err
+	}
+
+	return fixtureResponse(req, http.StatusOK, mime.TypeByExtension(filepath.Ext(files[0])), body), nil
+}
+
+func (t *fixtureTransport) recordFixture(req *http.Request, name string) (*http.Response, error) {
+	resp, err := http.DefaultTransport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode == http.StatusOK {
+		if err := os.MkdirAll(t.dir, 0o750); err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(name+fixtureExtension(resp.Header.Get("Content-Type")), body, 0o600); err != nil {
+			return nil, fmt.Errorf("cannot save fixture: %w", err)
+		}
+	}
+
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	return resp, nil
+}
+
+func fixtureResponse(req *http.Request, status int, contentType string, body []byte) *http.Response {
+	return &http.Response{
+		Status:        http.StatusText(status),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": []string{contentType}},
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
+
+var unsafeFixtureChars = regexp.MustCompile(`[^a-zA-Z0-9.-]+`)
+
+// fixtureName returns file name (without extension) for given url.
+// Too long names are shortened and suffixed with hash of the url.
+func fixtureName(u *url.URL) string {
+	name := u.Host + u.EscapedPath()
+	if u.RawQuery != "" {
+		name += "?" + u.RawQuery
+	}
+	name = strings.Trim(unsafeFixtureChars.ReplaceAllString(name, "_"), "_")
+
+	const maxLength = 100
+	if len(name) > maxLength {
+		sum := sha1.Sum([]byte(u.String()))
+		name = name[:maxLength-9] + "_" + hex.EncodeToString(sum[:4])
+	}
+
+	return name
+}
+
+func fixtureExtension(contentType string) string {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return ".html"
+	}
+
+	switch {
+	case strings.Contains(mediaType, "xml"):
+		return ".xml"
+	case strings.HasPrefix(mediaType, "text/plain"):
+		return ".txt"
+	default:
+		return ".html"
+	}
+}
+
+// useFixtures makes all collectors read pages from testdata/dir.
+// Returned func restores live transport.
+func useFixtures(dir string) func() {
+	previous := transport
+	transport = &fixtureTransport{
+		dir:    filepath.Join("testdata", dir),
+		record: *record,
+	}
+
+	// recorded pages can be served without delays
+	previousConfig := config
+	if !*record {
+		config.Delay = 0
+		config.RetryDelay = time.Millisecond
+	}
+
+	return func() {
+		transport = previous
+		config = previousConfig
+	}
+}
@@ -0,0 +1,121 @@
+package scraper
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gocolly/colly"
+)
+
+// Known tells which books are already stored and were scraped recently, so they don't need to be scraped again.
+type Known interface {
+	// KnownURL reports whether book with given page url is stored.
+	KnownURL(url string) bool
+	// KnownISBN reports whether book with given normalized ISBN is stored.
+	KnownISBN(isbn string) bool
+}
+
+// KnownBooks is Known backed by sets of urls and ISBNs.
+type KnownBooks struct {
+	urls  map[string]struct{}
+	isbns map[string]struct{}
+}
+
+// NewKnownBooks creates KnownBooks from urls and ISBNs of stored books.
+func NewKnownBooks(urls, isbns []string) *KnownBooks {
+	known := &KnownBooks{
+		urls:  make(map[string]struct{}, len(urls)),
+		isbns: make(map[string]struct{}, len(isbns)),
+	}
+
+	for _, url := range urls {
+		known.urls[url] = struct{}{}
+	}
+	for _, isbn := range isbns {
+		known.isbns[isbn] = struct{}{}
+	}
+
+	return known
+}
+
+// KnownURL implements Known.
+func (k *KnownBooks) KnownURL(url string) bool {
+	_, ok := k.urls[url]
+	return ok
+}
+
+// KnownISBN implements Known.
+func (k *KnownBooks) KnownISBN(isbn string) bool {
+	_, ok := k.isbns[isbn]
+	return ok
+}
+
+type knownKey struct{}
+
+// WithKnown attaches known books to context, so scraping with that context is incremental:
+// pages of known books are not visited and pagination stops at listing pages
+// containing only known books.
+//
+// Scraping without known books visits every page.
+func WithKnown(ctx context.Context, known Known) context.Context {
+	return context.WithValue(ctx, knownKey{}, known)
+}
+
+// knownFromContext returns known books attached to context or nil.
+func knownFromContext(ctx context.Context) Known {
+	known, _ := ctx.Value(knownKey{}).(Known)
+	return known
+}
+
+// listingPage counts book links on listing page.
+//
+// Callbacks of one response are called sequentially and callbacks
+// of book links are registered before pagination ones,
+// so links are counted before next page is visited.
+type listingPage struct {
+	links int
+	known int
+}
+
+// listing returns counters of listing page the element belongs to.
+func listing(h *colly.HTMLElement) *listingPage {
+	// request context is shared with requests made from its callbacks,
+	// so pages are stored per url
+	key := fmt.Sprintf("listing %s", h.Request.URL)
+	page, ok := h.Request.Ctx.GetAny(key).(*listingPage)
+	if !ok {
+		page = &listingPage{}
+		h.Request.Ctx.Put(key, page)
+	}
+
+	return page
+}
+
+// visitBook visits book page linked from listing page unless book is already known.
+func visitBook(ctx context.Context, h *colly.HTMLElement, link string) error {
+	page := listing(h)
+	page.links++
+
+	if known := knownFromContext(ctx); known != nil && known.KnownURL(h.Request.AbsoluteURL(link)) {
+		page.known++
+		if report := reportFromContext(ctx); report != nil {
+			report.bookKnown()
+		}
+		return nil
+	}
+
+	return h.Request.Visit(link)
+}
+
+// visitNextPage visits next listing page unless current one contains only known books.
+func visitNextPage(ctx context.Context, h *colly.HTMLElement, link string) error {
+	if knownFromContext(ctx) == nil {
+		return h.Request.Visit(link)
+	}
+
+	if page := listing(h); page.links > 0 && page.links == page.known {
+		return nil
+	}
+
+	return h.Request.Visit(link)
+}
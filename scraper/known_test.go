@@ -0,0 +1,46 @@
+package scraper
+
+import (
+	"context"
+	"testing"
+
+	"github.com/matryer/is"
+)
+
+func TestScraperSkipsKnownBooks(t *testing.T) {
+	is := is.New(t)
+	defer useFixtures("dmkpress")()
+
+	known := NewKnownBooks([]string{"https://dmkpress.com/catalog/computer/databases/postgresql-iznutri/"}, nil)
+	ch, report := Scrape(WithKnown(context.Background(), known), "dmkpress")
+	books := collectBooks(ch, report)
+
+	is.Equal(len(books), 1)
+	is.Equal(books[0].Title, "Go на практике")
+	is.Equal(report.Sites[0].Pages, 3) // page of known book should not be visited
+}
+
+func TestScraperStopsAtListingWithOnlyKnownBooks(t *testing.T) {
+	is := is.New(t)
+	defer useFixtures("dmkpress")()
+
+	known := NewKnownBooks([]string{"https://dmkpress.com/catalog/computer/programming/go-na-praktike/"}, nil)
+	ch, report := Scrape(WithKnown(context.Background(), known), "dmkpress")
+	books := collectBooks(ch, report)
+
+	is.Equal(len(books), 0)
+	is.Equal(report.Sites[0].Pages, 1) // next listing pages should not be visited
+	is.Equal(report.Sites[0].Known, 1)
+	is.True(!report.Failed()) // site without new books is not failed
+}
+
+func TestFeedSkipsKnownBooks(t *testing.T) {
+	is := is.New(t)
+	defer useFixtures("nostarch")()
+
+	known := NewKnownBooks(nil, []string{"9781718503106"})
+	books := collectBooks(Scrape(WithKnown(context.Background(), known), "nostarch"))
+
+	is.Equal(len(books), 1)
+	is.Equal(books[0].Title, "Python Crash Course, 3rd Edition")
+}
@@ -0,0 +1,319 @@
+package scraper
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/gocolly/colly"
+	"github.com/tommsawyer/itbooks/isbn"
+)
+
+// metadata is book information that publishers embed into pages for search engines.
+type metadata struct {
+	Name          string
+	ISBN          string
+	Authors       []string
+	Image         string
+	Description   string
+	Price         string
+	Currency      string
+	DatePublished string
+	Availability  string
+}
+
+// fillFromMetadata fills book fields that site selectors left empty
+// using JSON-LD, microdata and OpenGraph tags of the page.
+func fillFromMetadata(h *colly.HTMLElement, book *Book) {
+	meta := extractMetadata(document(h.DOM))
+
+	if book.Title == "" {
+		book.Title = meta.Name
+	}
+	if _, err := isbn.Parse(book.ISBN); err != nil && meta.ISBN != "" {
+		book.ISBN = meta.ISBN
+	}
+	if len(book.Authors) == 0 || (len(book.Authors) == 1 && book.Authors[0] == "") {
+		book.Authors = meta.Authors
+	}
+	if book.ImageURL == "" && meta.Image != "" {
+		book.ImageURL = h.Request.AbsoluteURL(meta.Image)
+	}
+	if book.Description == "" {
+		book.Description = meta.Description
+	}
+	if book.Status == StatusUnknown {
+		book.Status = parseStatus(meta.Availability)
+	}
+
+	setPrice(book, meta.Price, meta.Currency)
+
+	if meta.DatePublished != "" && book.Details["date_published"] == "" {
+		if book.Details == nil {
+			book.Details = make(map[string]string)
+		}
+		book.Details["date_published"] = meta.DatePublished
+	}
+}
+
+// extractMetadata extracts metadata from document.
+// JSON-LD has the highest priority, then microdata and OpenGraph.
+func extractMetadata(doc *goquery.Selection) metadata {
+	var meta metadata
+
+	meta.merge(extractJSONLD(doc))
+	meta.merge(extractMicrodata(doc))
+	meta.merge(extractOpenGraph(doc))
+
+	return meta
+}
+
+// merge sets empty fields of m from other.
+func (m *metadata) merge(other metadata) {
+	set := func(field *string, value string) {
+		if *field == "" {
+			*field = strings.TrimSpace(value)
+		}
+	}
+
+	set(&m.Name, other.Name)
+	set(&m.ISBN, other.ISBN)
+	set(&m.Image, other.Image)
+	set(&m.Description, other.Description)
+	set(&m.Price, other.Price)
+	set(&m.Currency, other.Currency)
+	set(&m.DatePublished, other.DatePublished)
+	set(&m.Availability, other.Availability)
+	if len(m.Authors) == 0 {
+		m.Authors = other.Authors
+	}
+}
+
+// extractJSONLD extracts first schema.org Book or Product from JSON-LD scripts.
+func extractJSONLD(doc *goquery.Selection) metadata {
+	var meta metadata
+
+	doc.Find(`script[type="application/ld+json"]`).EachWithBreak(func(_ int, s *goquery.Selection) bool {
+		var data any
+		if err := json.Unmarshal([]byte(s.Text()), &data); err != nil {
+			return true
+		}
+
+		node := findJSONLDBook(data)
+		if node == nil {
+			return true
+		}
+
+		meta = metadata{
+			Name:          jsonString(node["name"]),
+			ISBN:          jsonString(node["isbn"]),
+			Authors:       jsonNames(node["author"]),
+			Image:         jsonURL(node["image"]),
+			Description:   jsonString(node["description"]),
+			DatePublished: jsonString(node["datePublished"]),
+		}
+		if meta.ISBN == "" {
+			meta.ISBN = jsonString(node["gtin13"])
+		}
+
+		offers := jsonFirst(node["offers"])
+		if offer, ok := offers.(map[string]any); ok {
+			meta.Price = jsonString(offer["price"])
+			meta.Currency = jsonString(offer["priceCurrency"])
+			meta.Availability = jsonString(offer["availability"])
+		}
+
+		return false
+	})
+
+	return meta
+}
+
+// findJSONLDBook finds Book or Product node in JSON-LD document.
+// Document can be single node, list of nodes or graph.
+func findJSONLDBook(data any) map[string]any {
+	switch data := data.(type) {
+	case []any:
+		for _, item := range data {
+			if node := findJSONLDBook(item); node != nil {
+				return node
+			}
+		}
+	case map[string]any:
+		for _, t := range jsonStrings(data["@type"]) {
+			if t == "Book" || t == "Product" {
+				return data
+			}
+		}
+		return findJSONLDBook(data["@graph"])
+	}
+
+	return nil
+}
+
+// jsonString returns string or number as string.
+func jsonString(v any) string {
+	switch v := jsonFirst(v).(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return ""
+	}
+}
+
+func jsonStrings(v any) []string {
+	if list, ok := v.([]any); ok {
+		strs := make([]string, 0, len(list))
+		for _, item := range list {
+			strs = append(strs, jsonString(item))
+		}
+		return strs
+	}
+
+	return []string{jsonString(v)}
+}
+
+// jsonNames returns names of persons, e.g. "author": [{"@type": "Person", "name": "..."}].
+func jsonNames(v any) []string {
+	list, ok := v.([]any)
+	if !ok {
+		list = []any{v}
+	}
+
+	var names []string
+	for _, item := range list {
+		name := jsonString(item)
+		if person, ok := item.(map[string]any); ok {
+			name = jsonString(person["name"])
+		}
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+
+	return names
+}
+
+// jsonURL returns url of image, which can be url itself or ImageObject.
+func jsonURL(v any) string {
+	if image, ok := jsonFirst(v).(map[string]any); ok {
+		return jsonString(image["url"])
+	}
+
+	return jsonString(v)
+}
+
+// jsonFirst returns first element of list or value itself.
+func jsonFirst(v any) any {
+	if list, ok := v.([]any); ok {
+		if len(list) == 0 {
+			return nil
+		}
+		return list[0]
+	}
+
+	return v
+}
+
+// extractMicrodata extracts first schema.org Book or Product described with microdata.
+func extractMicrodata(doc *goquery.Selection) metadata {
+	var meta metadata
+
+	doc.Find("[itemscope][itemtype]").EachWithBreak(func(_ int, scope *goquery.Selection) bool {
+		itemType := scope.AttrOr("itemtype", "")
+		if !strings.HasSuffix(itemType, "schema.org/Book") && !strings.HasSuffix(itemType, "schema.org/Product") {
+			return true
+		}
+
+		// properties of nested items (e.g. author's name) are skipped,
+		// except offer which contains price
+		own := func(s *goquery.Selection) bool {
+			return s.Parent().Closest("[itemscope]").IsSelection(scope)
+		}
+		prop := func(name string) *goquery.Selection {
+			return scope.Find(fmt.Sprintf("[itemprop=%q]", name)).FilterFunction(func(_ int, s *goquery.Selection) bool {
+				return own(s)
+			}).First()
+		}
+
+		meta = metadata{
+			Name:          itemValue(prop("name")),
+			ISBN:          itemValue(prop("isbn")),
+			Image:         itemValue(prop("image")),
+			Description:   itemValue(prop("description")),
+			Price:         itemValue(scope.Find(`[itemprop="price"]`).First()),
+			Currency:      itemValue(scope.Find(`[itemprop="priceCurrency"]`).First()),
+			Availability:  itemValue(scope.Find(`[itemprop="availability"]`).First()),
+			DatePublished: itemValue(prop("datePublished")),
+		}
+
+		scope.Find(`[itemprop="author"]`).Each(func(_ int, author *goquery.Selection) {
+			if !own(author) {
+				return
+			}
+
+			name := itemValue(author)
+			if author.Is("[itemscope]") {
+				name = itemValue(author.Find(`[itemprop="name"]`).First())
+			}
+			if name != "" {
+				meta.Authors = append(meta.Authors, name)
+			}
+		})
+
+		return false
+	})
+
+	return meta
+}
+
+// itemValue returns value of microdata property.
+func itemValue(s *goquery.Selection) string {
+	if s.Length() == 0 {
+		return ""
+	}
+
+	for _, attr := range []string{"content", "src", "href", "datetime"} {
+		if value, ok := s.Attr(attr); ok {
+			return strings.TrimSpace(value)
+		}
+	}
+
+	return strings.TrimSpace(s.Text())
+}
+
+// extractOpenGraph extracts book information from OpenGraph meta tags.
+func extractOpenGraph(doc *goquery.Selection) metadata {
+	property := func(names ...string) string {
+		for _, name := range names {
+			if value := doc.Find(fmt.Sprintf("meta[property=%q]", name)).AttrOr("content", ""); value != "" {
+				return value
+			}
+		}
+		return ""
+	}
+
+	meta := metadata{
+		Name:          property("og:title"),
+		ISBN:          property("book:isbn"),
+		Image:         property("og:image"),
+		Description:   property("og:description"),
+		Price:         property("product:price:amount", "og:price:amount"),
+		Currency:      property("product:price:currency", "og:price:currency"),
+		DatePublished: property("book:release_date"),
+		Availability:  property("product:availability", "og:availability"),
+	}
+
+	doc.Find(`meta[property="book:author"]`).Each(func(_ int, s *goquery.Selection) {
+		// author could be link to profile page, which is useless as name
+		if author := s.AttrOr("content", ""); author != "" && !strings.HasPrefix(author, "http") {
+			meta.Authors = append(meta.Authors, author)
+		}
+	})
+
+	return meta
+}
@@ -0,0 +1,120 @@
+package scraper
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/matryer/is"
+)
+
+func TestExtractMetadata(t *testing.T) {
+	tests := []struct {
+		name string
+		html string
+		meta metadata
+	}{
+		{
+			name: "json-ld",
+			html: `<html><head><script type="application/ld+json">
+				{
+					"@context": "https://schema.org",
+					"@type": "Book",
+					"name": "Title",
+					"isbn": "978-5-4461-2246-2",
+					"author": [{"@type": "Person", "name": "First Author"}, "Second Author"],
+					"image": {"@type": "ImageObject", "url": "/cover.jpg"},
+					"description": "Description",
+					"datePublished": "2024-03-12",
+					"offers": [{"@type": "Offer", "price": "999.90", "priceCurrency": "RUB", "availability": "https://schema.org/PreOrder"}]
+				}
+			</script></head><body></body></html>`,
+			meta: metadata{
+				Name:          "Title",
+				ISBN:          "978-5-4461-2246-2",
+				Authors:       []string{"First Author", "Second Author"},
+				Image:         "/cover.jpg",
+				Description:   "Description",
+				Price:         "999.90",
+				Currency:      "RUB",
+				DatePublished: "2024-03-12",
+				Availability:  "https://schema.org/PreOrder",
+			},
+		},
+		{
+			name: "microdata",
+			html: `<html><body><div itemscope itemtype="http://schema.org/Book">
+				<h1 itemprop="name">Title</h1>
+				<div itemprop="author" itemscope itemtype="http://schema.org/Person"><span itemprop="name">Author</span></div>
+				<img itemprop="image" src="/cover.jpg">
+				<span itemprop="isbn">978-5-4461-2246-2</span>
+				<time itemprop="datePublished" datetime="2024-03-12">март 2024</time>
+				<div itemprop="description">Description</div>
+				<div itemprop="offers" itemscope itemtype="http://schema.org/Offer">
+					<meta itemprop="price" content="1000">
+					<meta itemprop="priceCurrency" content="RUB">
+					<link itemprop="availability" href="http://schema.org/InStock">
+				</div>
+			</div></body></html>`,
+			meta: metadata{
+				Name:          "Title",
+				ISBN:          "978-5-4461-2246-2",
+				Authors:       []string{"Author"},
+				Image:         "/cover.jpg",
+				Description:   "Description",
+				Price:         "1000",
+				Currency:      "RUB",
+				DatePublished: "2024-03-12",
+				Availability:  "http://schema.org/InStock",
+			},
+		},
+		{
+			name: "opengraph",
+			html: `<html><head>
+				<meta property="og:title" content="Title">
+				<meta property="og:image" content="https://example.com/cover.jpg">
+				<meta property="og:description" content="Description">
+				<meta property="book:isbn" content="9785446122462">
+				<meta property="book:author" content="https://example.com/authors/1">
+				<meta property="book:author" content="Author">
+				<meta property="book:release_date" content="2024-03-12">
+				<meta property="product:price:amount" content="1000">
+				<meta property="product:price:currency" content="RUB">
+				<meta property="product:availability" content="out of stock">
+			</head><body></body></html>`,
+			meta: metadata{
+				Name:          "Title",
+				ISBN:          "9785446122462",
+				Authors:       []string{"Author"},
+				Image:         "https://example.com/cover.jpg",
+				Description:   "Description",
+				Price:         "1000",
+				Currency:      "RUB",
+				DatePublished: "2024-03-12",
+				Availability:  "out of stock",
+			},
+		},
+		{
+			name: "json-ld has priority",
+			html: `<html><head>
+				<meta property="og:title" content="OpenGraph Title">
+				<meta property="og:description" content="OpenGraph Description">
+				<script type="application/ld+json">{"@type": "Product", "name": "JSON-LD Title"}</script>
+			</head><body></body></html>`,
+			meta: metadata{
+				Name:        "JSON-LD Title",
+				Description: "OpenGraph Description",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			is := is.New(t)
+			doc, err := goquery.NewDocumentFromReader(strings.NewReader(tt.html))
+			is.NoErr(err)
+
+			is.Equal(extractMetadata(doc.Selection), tt.meta)
+		})
+	}
+}
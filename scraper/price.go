@@ -0,0 +1,83 @@
+package scraper
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// priceNumber matches amount with optional thousands separators and cents.
+var priceNumber = regexp.MustCompile(`\d[\d\s\x{00a0}\x{202f}]*(?:[.,]\d{1,2})?`)
+
+// currencySymbols maps lowercased currency symbols and abbreviations to ISO 4217 codes.
+var currencySymbols = []struct {
+	symbol string
+	code   string
+}{
+	{"₽", "RUB"},
+	{"руб", "RUB"},
+	{"rub", "RUB"},
+	{"$", "USD"},
+	{"usd", "USD"},
+	{"€", "EUR"},
+	{"eur", "EUR"},
+	{"£", "GBP"},
+	{"gbp", "GBP"},
+}
+
+// parsePrice parses price like "1 299 ₽", "999,90 руб." or "$39.99".
+// It returns amount and ISO 4217 code of currency, which is empty if text doesn't contain it.
+func parsePrice(text string) (float64, string, bool) {
+	number := priceNumber.FindString(text)
+	if number == "" {
+		return 0, "", false
+	}
+
+	number = strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '\t', '\n', '\u00a0', '\u202f':
+			return -1
+		case ',':
+			return '.'
+		default:
+			return r
+		}
+	}, number)
+
+	amount, err := strconv.ParseFloat(number, 64)
+	if err != nil || amount <= 0 {
+		return 0, "", false
+	}
+
+	return amount, parseCurrency(text), true
+}
+
+// parseCurrency returns ISO 4217 code of currency mentioned in text.
+func parseCurrency(text string) string {
+	text = strings.ToLower(text)
+	for _, c := range currencySymbols {
+		if strings.Contains(text, c.symbol) {
+			return c.code
+		}
+	}
+
+	return ""
+}
+
+// setPrice sets price of book parsed from text unless book already has one.
+// Currency is used if text doesn't mention it.
+func setPrice(book *Book, text, currency string) {
+	if book.Price > 0 {
+		return
+	}
+
+	amount, code, ok := parsePrice(text)
+	if !ok {
+		return
+	}
+	if code == "" {
+		code = strings.ToUpper(strings.TrimSpace(currency))
+	}
+
+	book.Price, book.Currency = amount, code
+}
@@ -0,0 +1,49 @@
+package scraper
+
+import (
+	"testing"
+
+	"github.com/matryer/is"
+)
+
+func TestParsePrice(t *testing.T) {
+	tests := []struct {
+		text     string
+		amount   float64
+		currency string
+		ok       bool
+	}{
+		{text: "1 299 ₽", amount: 1299, currency: "RUB", ok: true},
+		{text: "1\u00a0299\u00a0₽", amount: 1299, currency: "RUB", ok: true},
+		{text: "999,90 руб.", amount: 999.9, currency: "RUB", ok: true},
+		{text: "$39.99", amount: 39.99, currency: "USD", ok: true},
+		{text: "€ 45", amount: 45, currency: "EUR", ok: true},
+		{text: "1899", amount: 1899, ok: true},
+		{text: "Нет в наличии"},
+		{text: "0 ₽"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			is := is.New(t)
+
+			amount, currency, ok := parsePrice(tt.text)
+
+			is.Equal(ok, tt.ok)
+			is.Equal(amount, tt.amount)
+			is.Equal(currency, tt.currency)
+		})
+	}
+}
+
+func TestSetPriceKeepsExistingPrice(t *testing.T) {
+	is := is.New(t)
+
+	book := Book{}
+	setPrice(&book, "1899", "rub")
+	is.Equal(book.Price, 1899.0)
+	is.Equal(book.Currency, "RUB") // currency is taken from argument if text has none
+
+	setPrice(&book, "999 $", "")
+	is.Equal(book.Price, 1899.0) // price found first is kept
+}
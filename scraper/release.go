@@ -0,0 +1,100 @@
+package scraper
+
+import (
+	"strings"
+	"time"
+)
+
+// Status is availability of book.
+type Status string
+
+const (
+	// StatusUnknown is status of book whose site doesn't tell availability.
+	StatusUnknown Status = ""
+	// StatusPreorder is status of upcoming book that can be preordered.
+	StatusPreorder Status = "preorder"
+	// StatusInStock is status of book that can be bought.
+	StatusInStock Status = "in_stock"
+	// StatusOutOfPrint is status of book that is sold out or discontinued.
+	StatusOutOfPrint Status = "out_of_print"
+)
+
+// statusPhrases are lowercased phrases of sites and schema.org availability values.
+// Order matters: "нет в наличии" should be checked before "в наличии".
+var statusPhrases = []struct {
+	phrase string
+	status Status
+}{
+	{"preorder", StatusPreorder},
+	{"pre-order", StatusPreorder},
+	{"предзаказ", StatusPreorder},
+	{"скоро в продаже", StatusPreorder},
+	{"outofstock", StatusOutOfPrint},
+	{"out of stock", StatusOutOfPrint},
+	{"discontinued", StatusOutOfPrint},
+	{"soldout", StatusOutOfPrint},
+	{"out of print", StatusOutOfPrint},
+	{"нет в наличии", StatusOutOfPrint},
+	{"нет в продаже", StatusOutOfPrint},
+	{"тираж распродан", StatusOutOfPrint},
+	{"instock", StatusInStock},
+	{"in stock", StatusInStock},
+	{"limitedavailability", StatusInStock},
+	{"в наличии", StatusInStock},
+	{"в продаже", StatusInStock},
+}
+
+// parseStatus returns status described by text, e.g. "https://schema.org/PreOrder" or "Предзаказ".
+func parseStatus(text string) Status {
+	text = strings.ToLower(text)
+	for _, s := range statusPhrases {
+		if strings.Contains(text, s.phrase) {
+			return s.status
+		}
+	}
+
+	return StatusUnknown
+}
+
+// releaseDateLayouts are formats of release dates used by sites and feeds,
+// from the most precise to the least one.
+var releaseDateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02",
+	time.RFC1123Z,
+	time.RFC1123,
+	"02.01.2006",
+	"2006-01",
+	"01.2006",
+	"2006",
+}
+
+// parseReleaseDate parses release date. Dates without day or month
+// are parsed as the first day of the month or year.
+func parseReleaseDate(s string) (time.Time, bool) {
+	s = strings.TrimSpace(s)
+	for _, layout := range releaseDateLayouts {
+		if date, err := time.Parse(layout, s); err == nil {
+			return time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC), true
+		}
+	}
+
+	return time.Time{}, false
+}
+
+// fillRelease fills release date of book from its details
+// and marks book that isn't released yet as preorder.
+func fillRelease(book *Book, now time.Time) {
+	if book.ReleaseDate.IsZero() {
+		for _, key := range []string{"date_published", "year"} {
+			if date, ok := parseReleaseDate(book.Details[key]); ok {
+				book.ReleaseDate = date
+				break
+			}
+		}
+	}
+
+	if book.Status == StatusUnknown && book.ReleaseDate.After(now) {
+		book.Status = StatusPreorder
+	}
+}
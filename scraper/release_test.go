@@ -0,0 +1,81 @@
+package scraper
+
+import (
+	"testing"
+	"time"
+
+	"github.com/matryer/is"
+)
+
+func TestParseStatus(t *testing.T) {
+	tests := []struct {
+		text   string
+		status Status
+	}{
+		{text: "https://schema.org/PreOrder", status: StatusPreorder},
+		{text: "Предзаказ", status: StatusPreorder},
+		{text: "Скоро в продаже", status: StatusPreorder},
+		{text: "http://schema.org/InStock", status: StatusInStock},
+		{text: "В наличии", status: StatusInStock},
+		{text: "Нет в наличии", status: StatusOutOfPrint},
+		{text: "https://schema.org/Discontinued", status: StatusOutOfPrint},
+		{text: "out of stock", status: StatusOutOfPrint},
+		{text: "Купить", status: StatusUnknown},
+		{text: "", status: StatusUnknown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			is := is.New(t)
+			is.Equal(parseStatus(tt.text), tt.status)
+		})
+	}
+}
+
+func TestParseReleaseDate(t *testing.T) {
+	tests := []struct {
+		text string
+		date time.Time
+		ok   bool
+	}{
+		{text: "2024-03-12", date: date(2024, 3, 12), ok: true},
+		{text: "2024-01-30T22:00:00-05:00", date: date(2024, 1, 30), ok: true},
+		{text: "Tue, 09 Jan 2024 00:00:00 GMT", date: date(2024, 1, 9), ok: true},
+		{text: "Wed, 16 May 2018 00:00:00 +0300", date: date(2018, 5, 16), ok: true},
+		{text: "12.03.2024", date: date(2024, 3, 12), ok: true},
+		{text: "04.2024", date: date(2024, 4, 1), ok: true},
+		{text: " 2024 ", date: date(2024, 1, 1), ok: true},
+		{text: "весна 2024"},
+		{text: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			is := is.New(t)
+
+			date, ok := parseReleaseDate(tt.text)
+
+			is.Equal(ok, tt.ok)
+			is.Equal(date, tt.date)
+		})
+	}
+}
+
+func TestFillReleaseMarksUpcomingBooksAsPreorder(t *testing.T) {
+	is := is.New(t)
+	now := date(2024, 3, 1)
+
+	upcoming := Book{Details: map[string]string{"date_published": "2024-04-15"}}
+	fillRelease(&upcoming, now)
+	is.Equal(upcoming.ReleaseDate, date(2024, 4, 15))
+	is.Equal(upcoming.Status, StatusPreorder)
+
+	released := Book{Details: map[string]string{"year": "2023"}}
+	fillRelease(&released, now)
+	is.Equal(released.ReleaseDate, date(2023, 1, 1))
+	is.Equal(released.Status, StatusUnknown)
+
+	soldOut := Book{ReleaseDate: date(2024, 5, 1), Status: StatusOutOfPrint}
+	fillRelease(&soldOut, now)
+	is.Equal(soldOut.Status, StatusOutOfPrint) // status told by site is kept
+}
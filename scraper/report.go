@@ -0,0 +1,114 @@
+package scraper
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// Report contains scraping statistics of every site.
+//
+// It's complete only after books channel returned with it is closed.
+type Report struct {
+	Sites []*SiteReport
+}
+
+// Failed reports whether any of sites failed.
+func (r *Report) Failed() bool {
+	for _, site := range r.Sites {
+		if site.Failed() {
+			return true
+		}
+	}
+
+	return false
+}
+
+// String returns human readable summary of scraping.
+func (r *Report) String() string {
+	var builder strings.Builder
+
+	w := tabwriter.NewWriter(&builder, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "SITE\tPAGES\tBOOKS\tKNOWN\tNO ISBN\tHTTP ERRORS\tPARSE FAILURES\tDURATION\tSTATUS")
+	for _, site := range r.Sites {
+		fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%d\t%d\t%d\t%s\t%s\n",
+			site.Name,
+			site.Pages,
+			site.Books,
+			site.Known,
+			site.Synthetic,
+			site.HTTPErrors,
+			site.ParseFailures,
+			site.Duration.Round(time.Millisecond),
+			site.status(),
+		)
+	}
+	_ = w.Flush()
+
+	return builder.String()
+}
+
+// SiteReport contains scraping statistics of one site.
+type SiteReport struct {
+	Name  string
+	Pages int
+	Books int
+	// Known is number of known books that were skipped, see WithKnown.
+	Known int
+	// Synthetic is number of emitted books without valid ISBN, stored under synthetic key.
+	Synthetic  int
+	HTTPErrors int
+	// ParseFailures is number of invalid books that were dropped.
+	ParseFailures int
+	Duration      time.Duration
+	// Err is error returned by site scraper.
+	Err error
+
+	mu sync.Mutex
+}
+
+// Failed reports whether site returned error or found zero books, neither new nor known.
+func (r *SiteReport) Failed() bool {
+	return r.Err != nil || r.Books+r.Known == 0
+}
+
+func (r *SiteReport) status() string {
+	switch {
+	case r.Err != nil:
+		return "failed: " + r.Err.Error()
+	case r.Books+r.Known == 0:
+		return "failed: no books"
+	default:
+		return "ok"
+	}
+}
+
+func (r *SiteReport) pageVisited()     { r.inc(&r.Pages) }
+func (r *SiteReport) bookEmitted()     { r.inc(&r.Books) }
+func (r *SiteReport) bookKnown()       { r.inc(&r.Known) }
+func (r *SiteReport) bookWithoutISBN() { r.inc(&r.Synthetic) }
+func (r *SiteReport) httpFailed()      { r.inc(&r.HTTPErrors) }
+func (r *SiteReport) parseFailed()     { r.inc(&r.ParseFailures) }
+
+func (r *SiteReport) inc(count *int) {
+	r.mu.Lock()
+	*count++
+	r.mu.Unlock()
+}
+
+type reportKey struct{}
+
+// withReport attaches site report to context,
+// so collectors created with that context can update it.
+func withReport(ctx context.Context, report *SiteReport) context.Context {
+	return context.WithValue(ctx, reportKey{}, report)
+}
+
+// reportFromContext returns site report attached to context or nil.
+func reportFromContext(ctx context.Context) *SiteReport {
+	report, _ := ctx.Value(reportKey{}).(*SiteReport)
+	return report
+}
@@ -2,12 +2,20 @@ package scraper
 
 import (
 	"context"
+	_ "embed" // built-in site definitions
+	"errors"
+	"fmt"
 	"log"
 	"sync"
+	"time"
 
+	"github.com/tommsawyer/itbooks/isbn"
 	"golang.org/x/exp/maps"
+	"golang.org/x/exp/slices"
 )
 
+var errUnknownSite = errors.New("unknown site")
+
 // Site is book publisher that can be scraped.
 // It should send all parsed books to provided books channel.
 type Site interface {
@@ -21,52 +29,129 @@ func (f SiteFunc) Scrape(ctx context.Context, books chan<- Book) error {
 	return f(ctx, books)
 }
 
-var sites = map[string]Site{
+// builtinDefinitions are definitions of built-in sites that are scraped without Go code.
+//
+//go:embed sites.json
+var builtinDefinitions []byte
+
+var sites = withDefinitions(map[string]Site{
 	"piter":    SiteFunc(scrapePiter),
 	"dmkpress": SiteFunc(scrapeDMKPress),
 	"eksmo":    SiteFunc(scrapeEksmo),
+	"oreilly": &Feed{
+		URL:       "https://feeds.feedburner.com/oreilly/newbooks",
+		Publisher: "O'Reilly Media",
+		Language:  "en",
+	},
+	"manning": &Feed{
+		URL:       "https://www.manning.com/feeds/new-releases.atom",
+		Publisher: "Manning",
+		Language:  "en",
+	},
+	"nostarch": &Feed{
+		URL:       "https://nostarch.com/feeds/newbooks.xml",
+		Publisher: "No Starch Press",
+		Language:  "en",
+	},
+	"pragprog": &Feed{
+		URL:       "https://pragprog.com/titles.rss",
+		Publisher: "The Pragmatic Bookshelf",
+		Language:  "en",
+	},
+}, builtinDefinitions)
+
+// withDefinitions adds sites from JSON definitions to sites.
+// Built-in definitions are checked by tests, so invalid ones are programming errors.
+func withDefinitions(sites map[string]Site, definitions []byte) map[string]Site {
+	defined, err := parseDefinitions(definitions)
+	if err != nil {
+		panic(fmt.Sprintf("invalid built-in site definitions: %v", err))
+	}
+
+	maps.Copy(sites, defined)
+	return sites
 }
 
 // Book represents parsed book.
 type Book struct {
-	URL         string
-	ImageURL    string
+	URL      string
+	ImageURL string
+	// ISBN is normalized to ISBN-13 without hyphens before book is sent to consumer.
+	// Books without valid ISBN get synthetic key, see isbn.Synthetic.
 	ISBN        string
 	Title       string
 	Authors     []string
 	Description string
 	Publisher   string
-	Details     map[string]string
+	// ReleaseDate is zero if site doesn't tell it. Dates without day are
+	// stored as the first day of month or year.
+	ReleaseDate time.Time
+	Status      Status
+	// Price is zero if site doesn't tell it.
+	Price float64
+	// Currency is ISO 4217 code of price currency, e.g. "RUB".
+	Currency string
+	Details  map[string]string
 }
 
 // Scrape will scrape provided sites.
-func Scrape(ctx context.Context, names ...string) <-chan Book {
-	sitesToScrape := make([]Site, 0, len(names))
+//
+// Returned report is filled while scraping and complete once books channel is closed.
+// Cancel ctx when you stop reading books before channel is closed, otherwise scrapers will leak.
+func Scrape(ctx context.Context, names ...string) (<-chan Book, *Report) {
+	sitesToScrape := make(map[string]Site, len(names))
 	for _, name := range names {
-		sitesToScrape = append(sitesToScrape, sites[name])
+		sitesToScrape[name] = sites[name]
 	}
 
-	return run(ctx, sitesToScrape...)
+	return run(ctx, sitesToScrape, time.Now())
+}
+
+// Sites returns sorted names of all registered sites.
+func Sites() []string {
+	names := maps.Keys(sites)
+	slices.Sort(names)
+
+	return names
 }
 
 // ScrapeAll runs all scrapers.
-func ScrapeAll(ctx context.Context) <-chan Book {
-	return run(ctx, maps.Values(sites)...)
+func ScrapeAll(ctx context.Context) (<-chan Book, *Report) {
+	return run(ctx, sites, time.Now())
 }
 
-func run(ctx context.Context, sites ...Site) <-chan Book {
+// run scrapes sites. Books released after now are marked as preorders.
+func run(ctx context.Context, sites map[string]Site, now time.Time) (<-chan Book, *Report) {
 	books := make(chan Book)
+	report := &Report{}
+
+	names := maps.Keys(sites)
+	slices.Sort(names)
 
 	var wg sync.WaitGroup
-	wg.Add(len(sites))
-	for _, site := range sites {
-		go func(site Site) {
+	wg.Add(len(names))
+	for _, name := range names {
+		siteReport := &SiteReport{Name: name}
+		report.Sites = append(report.Sites, siteReport)
+
+		go func(site Site, report *SiteReport) {
 			defer wg.Done()
 
-			if err := site.Scrape(ctx, books); err != nil {
-				log.Print(err)
+			start := time.Now()
+			defer func() {
+				report.Duration = time.Since(start)
+			}()
+
+			if site == nil {
+				report.Err = errUnknownSite
+				return
 			}
-		}(site)
+
+			report.Err = scrapeSite(withReport(ctx, report), site, books, now)
+			if report.Err != nil {
+				log.Printf("[%s] scraping failed: %v", report.Name, report.Err)
+			}
+		}(sites[name], siteReport)
 	}
 
 	go func() {
@@ -74,5 +159,64 @@ func run(ctx context.Context, sites ...Site) <-chan Book {
 		close(books)
 	}()
 
-	return books
+	return books, report
+}
+
+// scrapeSite scrapes site and forwards valid books into books channel.
+func scrapeSite(ctx context.Context, site Site, books chan<- Book, now time.Time) error {
+	report := reportFromContext(ctx)
+	siteBooks := make(chan Book)
+	errc := make(chan error, 1)
+
+	go func() {
+		defer close(siteBooks)
+		errc <- site.Scrape(ctx, siteBooks)
+	}()
+
+	for book := range siteBooks {
+		if err := validate(book); err != nil {
+			report.parseFailed()
+			log.Printf("[%s] cannot parse %s: %v", report.Name, book.URL, err)
+			continue
+		}
+
+		normalized, err := isbn.Parse(book.ISBN)
+		if err != nil {
+			report.bookWithoutISBN()
+			log.Printf("[%s] book %s has invalid isbn %q, storing it under synthetic key", report.Name, book.URL, book.ISBN)
+			normalized = isbn.Synthetic(book.URL)
+		}
+		book.ISBN = normalized
+		fillRelease(&book, now)
+
+		select {
+		case books <- book:
+			report.bookEmitted()
+		case <-ctx.Done():
+			// nobody reads books anymore, drain them
+			// so site won't block forever on send
+			for range siteBooks {
+			}
+		}
+	}
+
+	if err := <-errc; err != nil {
+		return err
+	}
+
+	return ctx.Err()
+}
+
+// validate checks that book has all required fields.
+//
+// Book without isbn is still valid, it will be stored under synthetic key built from url.
+func validate(book Book) error {
+	switch {
+	case book.URL == "":
+		return errors.New("no url")
+	case book.Title == "":
+		return errors.New("no title")
+	default:
+		return nil
+	}
 }
@@ -2,36 +2,307 @@ package scraper
 
 import (
 	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"github.com/gocolly/colly"
 	"github.com/matryer/is"
 )
 
 func TestScraperParsesOneBook(t *testing.T) {
 	is := is.New(t)
 	site := &mockSite{
-		book: Book{
+		books: []Book{{
 			URL:         "url",
-			ISBN:        "isbn",
+			ISBN:        "9785446122462",
 			Title:       "title",
 			Authors:     []string{"author"},
 			ImageURL:    "image",
 			Description: "description",
 			Publisher:   "publisher",
 			Details:     map[string]string{"test": "test"},
+		}},
+	}
+
+	books, _ := run(context.Background(), map[string]Site{"mock": site}, time.Now())
+	parsedBook := <-books
+
+	is.Equal(parsedBook, site.books[0])
+}
+
+func TestScraperReportsEverySite(t *testing.T) {
+	is := is.New(t)
+
+	books, report := run(context.Background(), map[string]Site{
+		"ok": &mockSite{
+			books: []Book{
+				{URL: "url", ISBN: "9785446122462", Title: "title"},
+				{URL: "url2", ISBN: "9785446123100"},
+			},
 		},
+		"failed": &mockSite{err: errors.New("site is down")},
+		"empty":  &mockSite{},
+	}, time.Now())
+	for range books {
+	}
+
+	is.True(report.Failed()) // report should fail when some sites failed
+	is.Equal(len(report.Sites), 3)
+
+	empty, failed, ok := report.Sites[0], report.Sites[1], report.Sites[2]
+
+	is.Equal(empty.Name, "empty")
+	is.NoErr(empty.Err)
+	is.True(empty.Failed()) // site without books is failed
+
+	is.Equal(failed.Name, "failed")
+	is.Equal(failed.Err.Error(), "site is down")
+	is.True(failed.Failed()) // site with error is failed
+
+	is.Equal(ok.Name, "ok")
+	is.Equal(ok.Books, 1)
+	is.Equal(ok.ParseFailures, 1) // book without title is not emitted
+	is.True(!ok.Failed())
+}
+
+func TestScraperNormalizesISBN(t *testing.T) {
+	is := is.New(t)
+	site := &mockSite{
+		books: []Book{
+			{URL: "https://piter.com/book", ISBN: "978-5-4461-2246-2", Title: "title"},
+			{URL: "https://dmkpress.com/book", ISBN: "book", Title: "title"},
+		},
+	}
+
+	books, report := run(context.Background(), map[string]Site{"mock": site}, time.Now())
+
+	is.Equal((<-books).ISBN, "9785446122462")
+	is.Equal((<-books).ISBN, "url:dmkpress.com/book") // book without valid isbn gets synthetic key
+	for range books {
 	}
+	is.Equal(report.Sites[0].Books, 2)
+	is.Equal(report.Sites[0].Synthetic, 1)
+	is.Equal(report.Sites[0].ParseFailures, 0) // book without isbn is kept, so it isn't parse failure
+}
+
+func TestScraperReportsUnknownSite(t *testing.T) {
+	is := is.New(t)
+
+	books, report := Scrape(context.Background(), "unknown")
+	for range books {
+	}
+
+	is.Equal(len(report.Sites), 1)
+	is.Equal(report.Sites[0].Err, errUnknownSite)
+}
+
+func TestScraperStopsWhenConsumerGoesAway(t *testing.T) {
+	is := is.New(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	site := &endlessSite{done: make(chan struct{})}
+
+	books, report := run(ctx, map[string]Site{"endless": site}, time.Now())
+	<-books
+	cancel()
+
+	select {
+	case <-site.done:
+	case <-time.After(time.Second):
+		t.Fatal("site is still blocked on sending books")
+	}
+
+	_, ok := <-books
+	is.True(!ok) // books channel should be closed
+	is.True(errors.Is(report.Sites[0].Err, context.Canceled))
+}
+
+func TestCollectorAbortsRequestsOnCancel(t *testing.T) {
+	is := is.New(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			http.NotFound(w, r)
+			return
+		}
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	report := &SiteReport{Name: "test"}
+
+	start := time.Now()
+	err := visit(ctx, newCollector(withReport(ctx, report)), server.URL)
+
+	is.True(errors.Is(err, context.DeadlineExceeded)) // request should be aborted
+	is.True(time.Since(start) < time.Second)
+	is.Equal(report.HTTPErrors, 1)
+}
 
-	parsedBook := <-run(context.Background(), site)
+func TestCollectorRetriesTransientErrors(t *testing.T) {
+	is := is.New(t)
+	defer useConfig(Config{UserAgent: "test-agent", Retries: 3, RetryDelay: time.Millisecond})()
+
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			http.NotFound(w, r)
+			return
+		}
+		if r.UserAgent() != "test-agent" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		if atomic.AddInt32(&requests, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+	}))
+	defer server.Close()
+
+	report := &SiteReport{Name: "test"}
+	ctx := withReport(context.Background(), report)
+	err := visit(ctx, newCollector(ctx), server.URL)
 
-	is.Equal(parsedBook, site.book)
+	is.NoErr(err)
+	is.Equal(atomic.LoadInt32(&requests), int32(3)) // two failed requests should be retried
+	is.Equal(report.Pages, 1)
+	is.Equal(report.HTTPErrors, 0)
+}
+
+func TestCollectorGivesUpAfterRetries(t *testing.T) {
+	is := is.New(t)
+	defer useConfig(Config{Retries: 2, RetryDelay: time.Millisecond})()
+
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			http.NotFound(w, r)
+			return
+		}
+		atomic.AddInt32(&requests, 1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	report := &SiteReport{Name: "test"}
+	ctx := withReport(context.Background(), report)
+	err := visit(ctx, newCollector(ctx), server.URL)
+
+	is.NoErr(err)
+	is.Equal(atomic.LoadInt32(&requests), int32(3))
+	is.Equal(report.HTTPErrors, 1) // only the last failure should be counted
+}
+
+func TestCollectorDoesNotRetryClientErrors(t *testing.T) {
+	is := is.New(t)
+	defer useConfig(Config{Retries: 2, RetryDelay: time.Millisecond})()
+
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/robots.txt" {
+			atomic.AddInt32(&requests, 1)
+		}
+		http.NotFound(w, r)
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	err := visit(ctx, newCollector(ctx), server.URL)
+
+	is.NoErr(err)
+	is.Equal(atomic.LoadInt32(&requests), int32(1))
+}
+
+func TestCollectorRespectsRobotsTxt(t *testing.T) {
+	is := is.New(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			_, _ = w.Write([]byte("User-agent: *\nDisallow: /private\n"))
+			return
+		}
+		t.Errorf("page %s disallowed by robots.txt was requested", r.URL)
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	err := visit(ctx, newCollector(ctx), server.URL+"/private/book")
+
+	is.True(errors.Is(err, colly.ErrRobotsTxtBlocked))
 }
 
 type mockSite struct {
-	book Book
+	books []Book
+	err   error
 }
 
 func (p *mockSite) Scrape(ctx context.Context, books chan<- Book) error {
-	books <- p.book
-	return nil
+	for _, book := range p.books {
+		books <- book
+	}
+	return p.err
+}
+
+// endlessSite sends books until ctx is done, ignoring it while sending.
+type endlessSite struct {
+	done chan struct{}
+}
+
+func (s *endlessSite) Scrape(ctx context.Context, books chan<- Book) error {
+	defer close(s.done)
+
+	for ctx.Err() == nil {
+		books <- Book{URL: "url", ISBN: "9785446122462", Title: "title"}
+	}
+
+	return ctx.Err()
+}
+
+// useConfig sets collector config until returned func is called.
+func TestCollectorLimitsDomainsIndependently(t *testing.T) {
+	is := is.New(t)
+	defer useConfig(Config{Parallelism: 1})()
+
+	// concurrent is the largest number of requests served at once by both servers
+	var inFlight, concurrent int32
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			http.NotFound(w, r)
+			return
+		}
+
+		n := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		for highest := atomic.LoadInt32(&concurrent); n > highest; highest = atomic.LoadInt32(&concurrent) {
+			if atomic.CompareAndSwapInt32(&concurrent, highest, n) {
+				break
+			}
+		}
+
+		time.Sleep(100 * time.Millisecond)
+	})
+	site, cdn := httptest.NewServer(handler), httptest.NewServer(handler)
+	defer site.Close()
+	defer cdn.Close()
+
+	collector := newCollector(context.Background())
+	for _, url := range []string{site.URL + "/1", site.URL + "/2", cdn.URL + "/1", cdn.URL + "/2"} {
+		is.NoErr(collector.Visit(url))
+	}
+	collector.Wait()
+
+	is.Equal(atomic.LoadInt32(&concurrent), int32(2)) // every domain should get one request at a time
+}
+
+func useConfig(c Config) func() {
+	previous := config
+	config = c
+
+	return func() {
+		config = previous
+	}
 }
@@ -0,0 +1,367 @@
+package scraper
+
+import (
+	"context"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/matryer/is"
+)
+
+// recordedAt is time when pages in testdata were recorded,
+// books released after it are expected to be preorders.
+var recordedAt = date(2024, 5, 1)
+
+// recordedBooks are books that should be scraped from pages in testdata.
+var recordedBooks = []struct {
+	site  string
+	pages int
+	books []Book
+}{
+	{
+		site:  "piter",
+		pages: 5,
+		books: []Book{
+			{
+				ISBN:        "9785446122462",
+				URL:         "https://www.piter.com/collection/kompyutery-i-internet/product/grokaem-algoritmy-2-e-izd",
+				Title:       "Грокаем алгоритмы. 2-е изд.",
+				Authors:     []string{"Бхаргава Адитья"},
+				ImageURL:    "https://static-sl.insales.ru/images/products/1/4621/grokaem.jpg",
+				Description: "Алгоритмы – это всего лишь пошаговые инструкции решения задач.",
+				Publisher:   "Питер",
+				Price:       1199,
+				Currency:    "RUB",
+				ReleaseDate: date(2024, 1, 1),
+				Status:      StatusInStock,
+				Details:     map[string]string{"year": "2024"},
+			},
+			{
+				ISBN:        "9785446123100",
+				URL:         "https://www.piter.com/collection/kompyutery-i-internet/product/kubernetes-v-deystvii",
+				Title:       "Kubernetes в действии",
+				Authors:     []string{"Лукша Марко", "Иванов Иван"},
+				ImageURL:    "https://static-sl.insales.ru/images/products/1/7712/kubernetes.jpg",
+				Description: "Книга о том, как запускать приложения в Kubernetes.",
+				Publisher:   "Питер",
+				Price:       1499,
+				Currency:    "RUB",
+				ReleaseDate: date(2023, 1, 1),
+				Status:      StatusPreorder,
+				Details:     map[string]string{"year": "2023"},
+			},
+			{
+				ISBN:        "9785446120550",
+				URL:         "https://www.piter.com/collection/kompyutery-i-internet/product/chistaya-arhitektura",
+				Title:       "Чистая архитектура",
+				Authors:     []string{"Мартин Роберт"},
+				ImageURL:    "https://static-sl.insales.ru/images/products/1/1001/architecture.jpg",
+				Description: "Искусство разработки программного обеспечения.",
+				Publisher:   "Питер",
+				ReleaseDate: date(2022, 1, 1),
+				Details:     map[string]string{"year": "2022"},
+			},
+		},
+	},
+	{
+		site:  "dmkpress",
+		pages: 4,
+		books: []Book{
+			{
+				ISBN:        "9785970609873",
+				URL:         "https://dmkpress.com/catalog/computer/programming/go-na-praktike/",
+				Title:       "Go на практике",
+				Authors:     []string{"Батчер М.", "Фарина М."},
+				ImageURL:    "https://dmkpress.com/upload/iblock/123/go.jpg",
+				Description: "Практическое руководство по языку Go.",
+				Publisher:   "ДМК-Пресс",
+				Price:       1299,
+				Currency:    "RUB",
+				ReleaseDate: date(2024, 4, 1),
+				Status:      StatusInStock,
+			},
+			{
+				ISBN:        "9785970611234",
+				URL:         "https://dmkpress.com/catalog/computer/databases/postgresql-iznutri/",
+				Title:       "PostgreSQL изнутри",
+				Authors:     []string{"Егор Рогов"},
+				ImageURL:    "https://dmkpress.com/upload/iblock/456/postgres.jpg",
+				Description: "Книга об устройстве PostgreSQL.",
+				Publisher:   "ДМК-Пресс",
+				Price:       1599,
+				Currency:    "RUB",
+				ReleaseDate: date(2023, 12, 1),
+			},
+		},
+	},
+	{
+		site:  "eksmo",
+		pages: 4,
+		books: []Book{
+			{
+				ISBN:        "9785041188122",
+				URL:         "https://eksmo.ru/book/python-k-vershinam-masterstva-ITD1234567/",
+				Title:       "Python. К вершинам мастерства",
+				Authors:     []string{"Лучано Рамальо"},
+				ImageURL:    "https://cdn.eksmo.ru/v2/ITD1234567/COVER/cover1__w820.jpg",
+				Description: "Лучшее руководство по Python 3.",
+				Publisher:   "Эксмо",
+				Price:       1899,
+				Currency:    "RUB",
+				ReleaseDate: date(2024, 3, 12),
+				Status:      StatusInStock,
+				Details: map[string]string{
+					"year":           "12.03.2024",
+					"date_published": "2024-03-12",
+				},
+			},
+			{
+				ISBN:        "9785041971236",
+				URL:         "https://eksmo.ru/book/rust-dlya-professionalov-ITD1122334/",
+				Title:       "Rust для профессионалов",
+				Authors:     []string{"Брендон Смит"},
+				ImageURL:    "https://cdn.eksmo.ru/v2/ITD1122334/COVER/cover1__w820.jpg",
+				Description: "Как писать надёжный и быстрый код на Rust.",
+				Publisher:   "Эксмо",
+				Price:       1499,
+				Currency:    "RUB",
+				ReleaseDate: date(2025, 1, 1),
+				Status:      StatusPreorder, // not released when page was recorded
+				Details:     map[string]string{"year": "2025"},
+			},
+		},
+	},
+	{
+		site:  "bhv",
+		pages: 5,
+		books: []Book{
+			{
+				ISBN:        "9785977518956",
+				URL:         "https://bhv.ru/product/python-dlya-nachinayushhih/",
+				Title:       "Python для начинающих",
+				Authors:     []string{"Прохоренок Николай"},
+				ImageURL:    "https://bhv.ru/wp-content/uploads/2024/05/python.jpg",
+				Description: "Самоучитель по языку Python для начинающих программистов.",
+				Publisher:   "БХВ",
+				Price:       990,
+				Currency:    "RUB",
+				ReleaseDate: date(2024, 1, 1),
+				Status:      StatusInStock,
+				Details:     map[string]string{"year": "2024", "pages": "416"},
+			},
+			{
+				ISBN:        "9785977517348",
+				URL:         "https://bhv.ru/product/linux-glazami-hakera/",
+				Title:       "Linux глазами хакера",
+				Authors:     []string{"Флёнов Михаил", "Иванов Иван"},
+				ImageURL:    "https://bhv.ru/wp-content/uploads/2024/03/linux.jpg",
+				Description: "Рассмотрены вопросы настройки Linux на максимальную производительность и безопасность.",
+				Publisher:   "БХВ",
+				ReleaseDate: date(2024, 1, 1),
+				Status:      StatusOutOfPrint,
+				Details:     map[string]string{"year": "2024", "pages": "448"},
+			},
+			{
+				ISBN:        "9785977519212",
+				URL:         "https://bhv.ru/product/arduino-bolshaya-kniga-retseptov/",
+				Title:       "Arduino. Большая книга рецептов",
+				Authors:     []string{"Марголис Майкл"},
+				ImageURL:    "https://bhv.ru/wp-content/uploads/2023/11/arduino.jpg",
+				Description: "Более 200 рецептов для работы с Arduino.",
+				Publisher:   "БХВ",
+				ReleaseDate: date(2023, 1, 1),
+				Details:     map[string]string{"year": "2023", "pages": "896"},
+			},
+		},
+	},
+	{
+		site:  "alpina",
+		pages: 5,
+		books: []Book{
+			{
+				ISBN:        "9785907491243",
+				URL:         "https://alpinabook.ru/catalog/books-programming/283115/",
+				Title:       "Идеальная команда разработки",
+				Authors:     []string{"Стефан Баккер", "Анна Иванова"},
+				ImageURL:    "https://alpinabook.ru/upload/covers/283115.jpg",
+				Description: "Как собрать и удержать сильную команду разработчиков.",
+				Publisher:   "Альпина Паблишер",
+				Price:       1050.5,
+				Currency:    "RUB",
+				ReleaseDate: date(2024, 1, 1),
+				Details:     map[string]string{"year": "2024", "pages": "320", "format": "Бумажная книга, 60×90/16"},
+			},
+			{
+				ISBN:     "9785907491892",
+				URL:      "https://alpinabook.ru/catalog/books-programming/283201/",
+				Title:    "Продуктовый менеджмент",
+				Authors:  []string{"Марти Каган"},
+				ImageURL: "https://alpinabook.ru/upload/covers/283201.jpg",
+				// annotation is missing on the page, so it's taken from OpenGraph
+				Description: "Как создавать продукты, которые любят пользователи.",
+				Publisher:   "Альпина Паблишер",
+				ReleaseDate: date(2024, 1, 1),
+				Status:      StatusPreorder,
+				Details:     map[string]string{"year": "2024", "pages": "368", "format": "Бумажная книга, 70×100/16"},
+			},
+			{
+				ISBN:        "9785002231508",
+				URL:         "https://alpinabook.ru/catalog/books-programming/281904/",
+				Title:       "Ускоряйся!",
+				Authors:     []string{"Николь Форсгрен"},
+				ImageURL:    "https://alpinabook.ru/upload/covers/281904.jpg",
+				Description: "Наука DevOps: как создавать и масштабировать высокопроизводительные цифровые организации.",
+				Publisher:   "Альпина Паблишер",
+				ReleaseDate: date(2023, 1, 1),
+				Details:     map[string]string{"year": "2023", "pages": "288", "format": "Электронная книга"},
+			},
+		},
+	},
+	{
+		site:  "oreilly",
+		pages: 1,
+		books: []Book{
+			{
+				ISBN:        "9781098139292",
+				URL:         "https://www.oreilly.com/library/view/learning-go-2nd/9781098139285/",
+				Title:       "Learning Go, 2nd Edition",
+				Authors:     []string{"Jon Bodner"},
+				ImageURL:    "https://learning.oreilly.com/library/cover/9781098139285/250w/",
+				Description: "Go has rapidly become the preferred language for building web services.",
+				Publisher:   "O'Reilly Media",
+				ReleaseDate: date(2024, 1, 9),
+				Details:     map[string]string{"date_published": "Tue, 09 Jan 2024 00:00:00 GMT", "language": "en"},
+			},
+			{
+				ISBN:        "9781098108304",
+				URL:         "https://www.oreilly.com/library/view/fundamentals-of-data/9781098108298/",
+				Title:       "Fundamentals of Data Engineering",
+				Authors:     []string{"Joe Reis", "Matt Housley"},
+				ImageURL:    "https://learning.oreilly.com/library/cover/9781098108298/250w/",
+				Description: "Data engineering has grown rapidly in the past decade.",
+				Publisher:   "O'Reilly Media",
+				ReleaseDate: date(2022, 6, 14),
+				Details:     map[string]string{"date_published": "Tue, 14 Jun 2022 00:00:00 GMT", "language": "en"},
+			},
+		},
+	},
+	{
+		site:  "manning",
+		pages: 1,
+		books: []Book{
+			{
+				ISBN:        "9781617297618",
+				URL:         "https://www.manning.com/books/kubernetes-in-action-second-edition",
+				Title:       "Kubernetes in Action, Second Edition",
+				Authors:     []string{"Marko Lukša", "Kevin Conner"},
+				ImageURL:    "https://images.manning.com/book/kubernetes-in-action.png",
+				Description: "Kubernetes in Action, Second Edition teaches you to use Kubernetes to deploy container-based distributed applications.",
+				Publisher:   "Manning",
+				ReleaseDate: date(2024, 1, 30),
+				Details:     map[string]string{"date_published": "2024-01-30T00:00:00Z", "language": "en"},
+			},
+			{
+				ISBN:        "9781617298424",
+				URL:         "https://www.manning.com/books/grokking-deep-reinforcement-learning",
+				Title:       "Grokking Deep Reinforcement Learning",
+				Authors:     []string{"Miguel Morales"},
+				Description: "Grokking Deep Reinforcement Learning uses engaging exercises to teach you how to build deep learning systems.",
+				Publisher:   "Manning",
+				ReleaseDate: date(2024, 1, 15),
+				Details:     map[string]string{"date_published": "2024-01-15T00:00:00Z", "language": "en"},
+			},
+		},
+	},
+	{
+		site:  "nostarch",
+		pages: 1,
+		books: []Book{
+			{
+				ISBN:        "9781718503106",
+				URL:         "https://nostarch.com/rust-programming-language-2nd-edition",
+				Title:       "The Rust Programming Language, 2nd Edition",
+				Authors:     []string{"Steve Klabnik", "Carol Nichols"},
+				ImageURL:    "https://nostarch.com/sites/default/files/styles/uc_product/public/RustProgrammingLanguage2e.png",
+				Description: "The official book on the Rust programming language.",
+				Publisher:   "No Starch Press",
+				ReleaseDate: date(2023, 2, 28),
+				Details:     map[string]string{"date_published": "2023-02-28", "language": "en"},
+			},
+			{
+				ISBN:        "9781718502703",
+				URL:         "https://nostarch.com/python-crash-course-3rd-edition",
+				Title:       "Python Crash Course, 3rd Edition",
+				Authors:     []string{"Eric Matthes"},
+				ImageURL:    "https://nostarch.com/sites/default/files/styles/uc_product/public/PythonCrashCourse3e.png",
+				Description: "The best-selling Python book in the world.",
+				Publisher:   "No Starch Press",
+				ReleaseDate: date(2023, 1, 10),
+				Details:     map[string]string{"date_published": "2023-01-10", "language": "en"},
+			},
+		},
+	},
+	{
+		site:  "pragprog",
+		pages: 1,
+		books: []Book{
+			{
+				ISBN:        "9781680502992",
+				URL:         "https://pragprog.com/titles/elixir16/programming-elixir-1-6/",
+				Title:       "Programming Elixir 1.6",
+				Authors:     []string{"Dave Thomas"},
+				ImageURL:    "https://pragprog.com/titles/elixir16/programming-elixir-1-6/elixir16.jpg",
+				Description: "This book is the introduction to Elixir for experienced programmers.",
+				Publisher:   "The Pragmatic Bookshelf",
+				ReleaseDate: date(2018, 5, 16),
+				Details:     map[string]string{"date_published": "Wed, 16 May 2018 00:00:00 +0000", "language": "en"},
+			},
+			{
+				ISBN:        "9781680508161",
+				URL:         "https://pragprog.com/titles/hwrust/hands-on-rust/",
+				Title:       "Hands-on Rust",
+				Authors:     []string{"Herbert Wolverson"},
+				ImageURL:    "https://pragprog.com/titles/hwrust/hands-on-rust/hwrust.jpg",
+				Description: "Rust is an exciting new programming language combining the power of C with memory safety.",
+				Publisher:   "The Pragmatic Bookshelf",
+				ReleaseDate: date(2021, 7, 20),
+				Details:     map[string]string{"date_published": "Tue, 20 Jul 2021 00:00:00 +0000", "language": "en"},
+			},
+		},
+	},
+}
+
+func TestSitesScrapeRecordedPages(t *testing.T) {
+	for _, tt := range recordedBooks {
+		t.Run(tt.site, func(t *testing.T) {
+			is := is.New(t)
+			defer useFixtures(tt.site)()
+
+			var books []Book
+			ch, report := run(context.Background(), map[string]Site{tt.site: sites[tt.site]}, recordedAt)
+			for book := range ch {
+				books = append(books, book)
+			}
+
+			is.Equal(sortBooks(books), sortBooks(tt.books))
+			is.Equal(report.Sites[0].Pages, tt.pages)
+			is.Equal(report.Sites[0].HTTPErrors, 0)
+			is.Equal(report.Sites[0].ParseFailures, 0)
+		})
+	}
+}
+
+// sortBooks returns copy of books sorted by url,
+// because pages are visited concurrently and books come in random order.
+func sortBooks(books []Book) []Book {
+	sorted := append([]Book(nil), books...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].URL < sorted[j].URL
+	})
+
+	return sorted
+}
+
+func date(year, month, day int) time.Time {
+	return time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+}
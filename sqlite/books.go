@@ -0,0 +1,356 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+
+	"github.com/tommsawyer/itbooks/isbn"
+	"github.com/tommsawyer/itbooks/storage"
+)
+
+var bookColumns = []string{
+	"id",
+	"isbn",
+	"url",
+	"title",
+	"image",
+	"description",
+	"authors",
+	"properties",
+	"publisher",
+	"release_date",
+	"status",
+	"price",
+	"currency",
+	"created_at",
+	"updated_at",
+}
+
+// scanBook scans row selected with bookColumns.
+func scanBook(row interface{ Scan(dest ...any) error }) (*storage.Book, error) {
+	var (
+		b           storage.Book
+		authors     string
+		properties  sql.NullString
+		releaseDate sql.NullTime
+		status      sql.NullString
+		price       sql.NullFloat64
+		currency    sql.NullString
+	)
+
+	err := row.Scan(
+		&b.ID,
+		&b.ISBN,
+		&b.URL,
+		&b.Title,
+		&b.Image,
+		&b.Description,
+		&authors,
+		&properties,
+		&b.Publisher,
+		&releaseDate,
+		&status,
+		&price,
+		&currency,
+		&b.CreatedAt,
+		&b.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	if err := json.Unmarshal([]byte(authors), &names); err != nil {
+		return nil, fmt.Errorf("cannot decode authors: %w", err)
+	}
+	if len(names) > 0 {
+		b.Authors = names
+	}
+	b.ReleaseDate = releaseDate.Time
+	b.Status = status.String
+	b.Price = price.Float64
+	b.Currency = currency.String
+
+	if properties.Valid {
+		if err := json.Unmarshal([]byte(properties.String), &b.Properties); err != nil {
+			return nil, fmt.Errorf("cannot decode properties: %w", err)
+		}
+	}
+
+	return &b, nil
+}
+
+// UpsertBook creates book and returns ID.
+//
+// If row with the same ISBN already exists it will just update fields of existing row
+// and returns id of old book. Semantics are the same as of postgres.Store.UpsertBook.
+func (s *Store) UpsertBook(ctx context.Context, params storage.UpsertBookParams) (int64, error) {
+	var id int64
+
+	err := s.inTransaction(ctx, func(ctx context.Context) error {
+		if err := s.adoptSyntheticBook(ctx, params); err != nil {
+			return err
+		}
+
+		var err error
+		id, err = s.upsertBook(ctx, params)
+		if err != nil {
+			return err
+		}
+
+		if params.Price > 0 {
+			return s.addBookPrice(ctx, id, params.Price, params.Currency)
+		}
+
+		return nil
+	})
+
+	return id, err
+}
+
+func (s *Store) upsertBook(ctx context.Context, params storage.UpsertBookParams) (int64, error) {
+	authors := params.Authors
+	if authors == nil {
+		authors = []string{}
+	}
+	encodedAuthors, err := json.Marshal(authors)
+	if err != nil {
+		return 0, fmt.Errorf("cannot encode authors: %w", err)
+	}
+
+	var properties sql.NullString
+	if params.Properties != nil {
+		encoded, err := json.Marshal(params.Properties)
+		if err != nil {
+			return 0, fmt.Errorf("cannot encode properties: %w", err)
+		}
+		properties = sql.NullString{String: string(encoded), Valid: true}
+	}
+
+	var releaseDate sql.NullString
+	if !params.ReleaseDate.IsZero() {
+		releaseDate = sql.NullString{String: params.ReleaseDate.Format("2006-01-02"), Valid: true}
+	}
+
+	query, args, err := builder.Insert("books").Columns(
+		"isbn", "url", "title", "image",
+		"description", "authors", "properties", "publisher",
+		"release_date", "status", "price", "currency",
+	).Values(
+		params.ISBN, params.URL, params.Title, params.Image,
+		params.Description, string(encodedAuthors), properties, params.Publisher,
+		releaseDate,
+		sql.NullString{String: params.Status, Valid: params.Status != ""},
+		sql.NullFloat64{Float64: params.Price, Valid: params.Price > 0},
+		sql.NullString{String: params.Currency, Valid: params.Price > 0},
+	).Suffix(`
+      ON CONFLICT(isbn) DO UPDATE
+      SET
+        title=excluded.title,
+        url=excluded.url,
+        image=excluded.image,
+        authors=excluded.authors,
+        properties=excluded.properties,
+        publisher=excluded.publisher,
+        description=excluded.description,
+        release_date=excluded.release_date,
+        status=excluded.status,
+        price=COALESCE(excluded.price, books.price),
+        currency=COALESCE(excluded.currency, books.currency),
+        updated_at=CURRENT_TIMESTAMP
+    `,
+	).Suffix("RETURNING id").ToSql()
+	if err != nil {
+		return 0, err
+	}
+
+	var id int64
+
+	err = s.db(ctx).QueryRowContext(ctx, query, args...).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("cannot create book: %w", err)
+	}
+
+	return id, nil
+}
+
+// adoptSyntheticBook moves book stored under synthetic key to its real ISBN.
+func (s *Store) adoptSyntheticBook(ctx context.Context, params storage.UpsertBookParams) error {
+	if isbn.IsSynthetic(params.ISBN) || params.URL == "" {
+		return nil
+	}
+
+	query, args, err := builder.Update("books").
+		Set("isbn", params.ISBN).
+		Where(sq.Eq{"url": params.URL}).
+		Where(sq.Like{"isbn": isbn.SyntheticPrefix + "%"}).
+		Where("NOT EXISTS (SELECT 1 FROM books WHERE isbn = ?)", params.ISBN).
+		ToSql()
+	if err != nil {
+		return err
+	}
+
+	if _, err := s.db(ctx).ExecContext(ctx, query, args...); err != nil {
+		return fmt.Errorf("cannot adopt book with synthetic isbn: %w", err)
+	}
+
+	return nil
+}
+
+// addBookPrice adds price to history of book prices.
+func (s *Store) addBookPrice(ctx context.Context, bookID int64, price float64, currency string) error {
+	query, args, err := builder.Insert("book_prices").
+		Columns("book_id", "price", "currency").
+		Values(bookID, price, currency).
+		ToSql()
+	if err != nil {
+		return err
+	}
+
+	if _, err := s.db(ctx).ExecContext(ctx, query, args...); err != nil {
+		return fmt.Errorf("cannot add book price: %w", err)
+	}
+
+	return nil
+}
+
+// UpdateBook updates given fields on book.
+func (s *Store) UpdateBook(ctx context.Context, id int64, fields storage.Fields) error {
+	query, params, err := builder.Update("books").
+		SetMap(fields).
+		Set("updated_at", time.Now().UTC()).
+		Where(sq.Eq{"id": id}).ToSql()
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db(ctx).ExecContext(ctx, query, params...)
+	return err
+}
+
+// GetBook returns first found book by given filter.
+//
+// Use squirrel for filtering, e.g. store.GetBook(ctx, sq.Eq{"id": id}) to get book by id.
+func (s *Store) GetBook(ctx context.Context, filter any) (*storage.Book, error) {
+	q := builder.Select(bookColumns...).From("books")
+	if filter != nil {
+		q = q.Where(filter)
+	}
+
+	query, params, err := q.OrderBy("created_at", "id").Limit(1).ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	book, err := scanBook(s.db(ctx).QueryRowContext(ctx, query, params...))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("cannot get book: %w", storage.ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot get book: %w", err)
+	}
+
+	return book, nil
+}
+
+// FindBooks returns books by given filter.
+//
+// Use squirrel for filtering, e.g. store.FindBooks(ctx, sq.Eq{"publisher": publisher}) to get books of publisher.
+func (s *Store) FindBooks(ctx context.Context, filter any) ([]*storage.Book, error) {
+	q := builder.Select(bookColumns...).From("books")
+	if filter != nil {
+		q = q.Where(filter)
+	}
+
+	query, params, err := q.OrderBy("created_at", "id").ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db(ctx).QueryContext(ctx, query, params...)
+	if err != nil {
+		return nil, fmt.Errorf("cannot find books: %w", err)
+	}
+	defer rows.Close()
+
+	var books []*storage.Book
+	for rows.Next() {
+		book, err := scanBook(rows)
+		if err != nil {
+			return nil, fmt.Errorf("cannot scan book: %w", err)
+		}
+
+		books = append(books, book)
+	}
+
+	return books, rows.Err()
+}
+
+// FindBookKeys returns urls and ISBNs of books updated after given time, all books if it's zero.
+func (s *Store) FindBookKeys(ctx context.Context, updatedAfter time.Time) (urls []string, isbns []string, err error) {
+	q := builder.Select("url", "isbn").From("books")
+	if !updatedAfter.IsZero() {
+		q = q.Where("datetime(updated_at) > datetime(?)", updatedAfter.UTC().Format("2006-01-02 15:04:05"))
+	}
+
+	query, params, err := q.ToSql()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rows, err := s.db(ctx).QueryContext(ctx, query, params...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("cannot find book keys: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var url, isbn string
+		if err := rows.Scan(&url, &isbn); err != nil {
+			return nil, nil, fmt.Errorf("cannot scan book keys: %w", err)
+		}
+
+		urls = append(urls, url)
+		isbns = append(isbns, isbn)
+	}
+
+	return urls, isbns, rows.Err()
+}
+
+// FindBookPrices returns price history of book with given ISBN, from the oldest price to the newest one.
+func (s *Store) FindBookPrices(ctx context.Context, isbn string) ([]*storage.BookPrice, error) {
+	query, args, err := builder.
+		Select("p.id", "p.book_id", "p.price", "p.currency", "p.created_at").
+		From("book_prices p").
+		Join("books b ON b.id = p.book_id").
+		Where(sq.Eq{"b.isbn": isbn}).
+		OrderBy("p.created_at", "p.id").
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db(ctx).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("cannot find book prices: %w", err)
+	}
+	defer rows.Close()
+
+	var prices []*storage.BookPrice
+	for rows.Next() {
+		var price storage.BookPrice
+		if err := rows.Scan(&price.ID, &price.BookID, &price.Price, &price.Currency, &price.CreatedAt); err != nil {
+			return nil, fmt.Errorf("cannot scan book price: %w", err)
+		}
+
+		prices = append(prices, &price)
+	}
+
+	return prices, rows.Err()
+}
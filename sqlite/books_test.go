@@ -0,0 +1,203 @@
+package sqlite
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/matryer/is"
+	"github.com/tommsawyer/itbooks/storage"
+)
+
+func openTestStore(t *testing.T) (context.Context, *is.I, *Store) {
+	is := is.New(t)
+	ctx := context.Background()
+
+	store, err := Open(ctx, filepath.Join(t.TempDir(), "itbooks.db"))
+	is.NoErr(err) // sqlite should be created with migrations applied
+	t.Cleanup(func() {
+		if err := store.Close(); err != nil {
+			t.Errorf("cannot close sqlite: %v", err)
+		}
+	})
+
+	return ctx, is, store
+}
+
+func TestUpsertBookInsertUnexistingBook(t *testing.T) {
+	ctx, is, store := openTestStore(t)
+
+	params := storage.UpsertBookParams{
+		ISBN:        "isbn",
+		URL:         "url",
+		Title:       "title",
+		Image:       "image",
+		Description: "description",
+		Authors:     []string{"author", "another author"},
+		Publisher:   "publisher",
+		Properties: map[string]string{
+			"test": "test",
+		},
+		ReleaseDate: time.Date(2024, 3, 12, 0, 0, 0, 0, time.UTC),
+		Status:      "preorder",
+		Price:       1299,
+		Currency:    "RUB",
+	}
+	id, err := store.UpsertBook(ctx, params)
+	is.NoErr(err) // we can create book
+
+	book, err := store.GetBook(ctx, sq.Eq{"id": id})
+	is.NoErr(err) // we should be able to get created book by id
+
+	assertBookFieldsMatch(is, id, book, params)
+	is.Equal(book.Price, 1299.0)
+	is.Equal(book.Currency, "RUB")
+	is.True(!book.CreatedAt.IsZero())
+}
+
+func TestUpsertBookWithTheSameISBNUpdatesBook(t *testing.T) {
+	ctx, is, store := openTestStore(t)
+
+	params := storage.UpsertBookParams{
+		ISBN:     "isbn",
+		URL:      "url",
+		Title:    "title",
+		Authors:  []string{"author"},
+		Price:    1299,
+		Currency: "RUB",
+	}
+	oldID, err := store.UpsertBook(ctx, params)
+	is.NoErr(err)
+
+	updatedParams := storage.UpsertBookParams{
+		ISBN:        "isbn",
+		URL:         "url2",
+		Title:       "title2",
+		Image:       "image2",
+		Description: "description2",
+		Authors:     []string{"author2"},
+		Publisher:   "publisher2",
+	}
+	newID, err := store.UpsertBook(ctx, updatedParams)
+	is.NoErr(err)
+
+	is.Equal(oldID, newID) // book should be updated, not created
+
+	book, err := store.GetBook(ctx, sq.Eq{"id": newID})
+	is.NoErr(err)
+
+	assertBookFieldsMatch(is, newID, book, updatedParams)
+	is.Equal(book.Price, 1299.0) // unknown price shouldn't erase stored one
+}
+
+func TestUpsertBookAdoptsBookWithSyntheticISBN(t *testing.T) {
+	ctx, is, store := openTestStore(t)
+
+	params := storage.UpsertBookParams{
+		ISBN:  "url:dmkpress.com/book",
+		URL:   "https://dmkpress.com/book",
+		Title: "title",
+	}
+	oldID, err := store.UpsertBook(ctx, params)
+	is.NoErr(err)
+
+	params.ISBN = "9785970609873"
+	newID, err := store.UpsertBook(ctx, params)
+	is.NoErr(err)
+
+	is.Equal(oldID, newID) // book with synthetic isbn should get real isbn
+}
+
+func TestFindBooks(t *testing.T) {
+	ctx, is, store := openTestStore(t)
+
+	_, err := store.UpsertBook(ctx, storage.UpsertBookParams{ISBN: "isbn", Title: "title"})
+	is.NoErr(err)
+
+	updatedBook := storage.UpsertBookParams{ISBN: "isbn2", Title: "title"}
+	id, err := store.UpsertBook(ctx, updatedBook)
+	is.NoErr(err)
+	is.NoErr(store.UpdateBook(ctx, id, storage.Fields{"title": "updated"}))
+	updatedBook.Title = "updated"
+
+	books, err := store.FindBooks(ctx, sq.Eq{"title": "updated"})
+	is.NoErr(err)
+
+	is.Equal(len(books), 1)
+	assertBookFieldsMatch(is, id, books[0], updatedBook)
+
+	urls, isbns, err := store.FindBookKeys(ctx, time.Time{})
+	is.NoErr(err)
+	is.Equal(len(urls), 2)
+	is.Equal(isbns, []string{"isbn", "isbn2"})
+
+	urls, _, err = store.FindBookKeys(ctx, time.Now().Add(-time.Hour))
+	is.NoErr(err)
+	is.Equal(len(urls), 2) // books were updated recently
+
+	urls, _, err = store.FindBookKeys(ctx, time.Now().Add(time.Hour))
+	is.NoErr(err)
+	is.Equal(len(urls), 0)
+}
+
+func TestUpsertBookAddsPriceToHistory(t *testing.T) {
+	ctx, is, store := openTestStore(t)
+
+	params := storage.UpsertBookParams{ISBN: "isbn", Title: "title", Price: 1299, Currency: "RUB"}
+	id, err := store.UpsertBook(ctx, params)
+	is.NoErr(err)
+
+	params.Price = 1499.5
+	_, err = store.UpsertBook(ctx, params)
+	is.NoErr(err)
+
+	params.Price = 0 // unknown price is not stored
+	_, err = store.UpsertBook(ctx, params)
+	is.NoErr(err)
+
+	prices, err := store.FindBookPrices(ctx, "isbn")
+	is.NoErr(err)
+
+	is.Equal(len(prices), 2)
+	is.Equal(prices[0].BookID, id)
+	is.Equal(prices[0].Price, 1299.0)
+	is.Equal(prices[1].Price, 1499.5)
+}
+
+func TestOpenKeepsBooksOfExistingDatabase(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "itbooks.db")
+
+	store, err := Open(ctx, path)
+	is.NoErr(err)
+	_, err = store.UpsertBook(ctx, storage.UpsertBookParams{ISBN: "isbn", Title: "title"})
+	is.NoErr(err)
+	is.NoErr(store.Close())
+
+	store, err = Open(ctx, path)
+	is.NoErr(err) // migrations shouldn't fail on migrated database
+	defer store.Close()
+
+	books, err := store.FindBooks(ctx, nil)
+	is.NoErr(err)
+	is.Equal(len(books), 1)
+}
+
+func assertBookFieldsMatch(is *is.I, id int64, book *storage.Book, params storage.UpsertBookParams) {
+	is.Helper()
+
+	is.Equal(book.ID, id)
+	is.Equal(book.ISBN, params.ISBN)
+	is.Equal(book.URL, params.URL)
+	is.Equal(book.Title, params.Title)
+	is.Equal(book.Image, params.Image)
+	is.Equal(book.Description, params.Description)
+	is.Equal(book.Authors, params.Authors)
+	is.Equal(book.Publisher, params.Publisher)
+	is.Equal(book.Properties, params.Properties)
+	is.True(book.ReleaseDate.Equal(params.ReleaseDate))
+	is.Equal(book.Status, params.Status)
+}
@@ -0,0 +1,120 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/golang-migrate/migrate/v4"
+	_ "github.com/golang-migrate/migrate/v4/database/sqlite" // sqlite:// database driver
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+	_ "modernc.org/sqlite" // sqlite sql driver, pure go so itbooks builds without cgo
+
+	"github.com/tommsawyer/itbooks/sqlite/migrations"
+	"github.com/tommsawyer/itbooks/storage"
+)
+
+// SchemePrefix is prefix of database uri that selects SQLite, e.g. sqlite:///var/lib/itbooks.db.
+const SchemePrefix = "sqlite://"
+
+var builder = sq.StatementBuilder.PlaceholderFormat(sq.Question)
+
+// DB is SQLite database or transaction.
+type DB interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// Store is book storage in SQLite file, so itbooks can run without database server.
+// It's safe for concurrent use.
+type Store struct {
+	sql *sql.DB
+}
+
+var _ storage.BookStore = (*Store)(nil)
+
+// Open opens SQLite database at given path, creating file if needed,
+// and applies embedded migrations. Store should be closed after use.
+func Open(ctx context.Context, path string) (*Store, error) {
+	if err := applyMigrations(path); err != nil {
+		return nil, fmt.Errorf("cannot apply sqlite migrations: %w", err)
+	}
+
+	// times are written in format understood by date functions of sqlite
+	db, err := sql.Open("sqlite", "file:"+path+"?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_time_format=sqlite")
+	if err != nil {
+		return nil, fmt.Errorf("cannot open sqlite: %w", err)
+	}
+	// SQLite allows single writer, concurrent writes would fail with "database is locked"
+	db.SetMaxOpenConns(1)
+
+	if err := db.PingContext(ctx); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("cannot open sqlite: %w", err)
+	}
+
+	return &Store{sql: db}, nil
+}
+
+// Close closes database.
+func (s *Store) Close() error {
+	return s.sql.Close()
+}
+
+func applyMigrations(path string) error {
+	src, err := iofs.New(migrations.Migrations, ".")
+	if err != nil {
+		return err
+	}
+
+	m, err := migrate.NewWithSourceInstance("iofs", src, "sqlite://"+path)
+	if err != nil {
+		return err
+	}
+
+	if err := m.Up(); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return err
+	}
+
+	sourceErr, dbErr := m.Close()
+	if sourceErr != nil {
+		return sourceErr
+	}
+
+	return dbErr
+}
+
+type transactionKey struct{}
+
+// inTransaction runs f with transaction attached to context.
+// Transaction is committed if f succeeds and rolled back otherwise.
+func (s *Store) inTransaction(ctx context.Context, f func(ctx context.Context) error) error {
+	if _, ok := ctx.Value(transactionKey{}).(*sql.Tx); ok {
+		return f(ctx)
+	}
+
+	tx, err := s.sql.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("cannot begin transaction: %w", err)
+	}
+
+	if err := f(context.WithValue(ctx, transactionKey{}, tx)); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// db returns transaction attached to context or database.
+func (s *Store) db(ctx context.Context) DB {
+	tx, ok := ctx.Value(transactionKey{}).(*sql.Tx)
+	if ok {
+		return tx
+	}
+
+	return s.sql
+}
@@ -0,0 +1,2 @@
+DROP TABLE book_prices;
+DROP TABLE books;
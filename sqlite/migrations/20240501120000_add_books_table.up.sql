@@ -0,0 +1,30 @@
+-- Schema mirrors postgres one: authors and properties are stored as JSON,
+-- release date as YYYY-MM-DD text.
+CREATE TABLE books (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  isbn TEXT UNIQUE NOT NULL,
+  url TEXT NOT NULL,
+  title TEXT NOT NULL,
+  authors TEXT NOT NULL DEFAULT '[]',
+  image TEXT NOT NULL,
+  description TEXT NOT NULL,
+  properties TEXT,
+  publisher TEXT NOT NULL,
+  release_date DATE,
+  status TEXT CHECK (status IN ('preorder', 'in_stock', 'out_of_print')),
+  price REAL,
+  currency TEXT,
+  published BOOLEAN NOT NULL DEFAULT FALSE,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE book_prices (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  book_id INTEGER NOT NULL REFERENCES books(id) ON DELETE CASCADE,
+  price REAL NOT NULL,
+  currency TEXT NOT NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX book_prices_book_id_idx ON book_prices (book_id, created_at);
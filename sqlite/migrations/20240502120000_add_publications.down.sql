@@ -0,0 +1,6 @@
+ALTER TABLE books ADD COLUMN published BOOLEAN NOT NULL DEFAULT FALSE;
+
+UPDATE books SET published = TRUE
+WHERE id IN (SELECT book_id FROM publications WHERE status = 'published');
+
+DROP TABLE publications;
@@ -0,0 +1,17 @@
+CREATE TABLE publications (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  book_id INTEGER NOT NULL REFERENCES books(id) ON DELETE CASCADE,
+  target TEXT NOT NULL,
+  channel TEXT NOT NULL,
+  message_id TEXT,
+  status TEXT NOT NULL CHECK (status IN ('published', 'failed')),
+  published_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX publications_book_id_idx ON publications (book_id, target, channel);
+
+-- Publications with empty channel count as published to every channel of target, see postgres migration.
+INSERT INTO publications (book_id, target, channel, status, published_at)
+SELECT id, 'telegram', '', 'published', updated_at FROM books WHERE published;
+
+ALTER TABLE books DROP COLUMN published;
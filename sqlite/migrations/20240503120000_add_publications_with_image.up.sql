@@ -0,0 +1,2 @@
+-- with_image is set if message was sent with image, see postgres migration.
+ALTER TABLE publications ADD COLUMN with_image BOOLEAN NOT NULL DEFAULT TRUE;
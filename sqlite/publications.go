@@ -0,0 +1,86 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	sq "github.com/Masterminds/squirrel"
+
+	"github.com/tommsawyer/itbooks/storage"
+)
+
+// AddPublication records publication of book and returns its ID.
+func (s *Store) AddPublication(ctx context.Context, params storage.AddPublicationParams) (int64, error) {
+	query, args, err := builder.Insert("publications").
+		Columns("book_id", "target", "channel", "message_id", "with_image", "status").
+		Values(
+			params.BookID, params.Target, params.Channel,
+			sql.NullString{String: params.MessageID, Valid: params.MessageID != ""},
+			params.WithImage,
+			params.Status,
+		).
+		Suffix("RETURNING id").
+		ToSql()
+	if err != nil {
+		return 0, err
+	}
+
+	var id int64
+	if err := s.db(ctx).QueryRowContext(ctx, query, args...).Scan(&id); err != nil {
+		return 0, fmt.Errorf("cannot add publication: %w", err)
+	}
+
+	return id, nil
+}
+
+// FindUnpublishedBooks returns books that aren't published to channel of target yet.
+// Publications with empty channel count as published to every channel of target.
+// Books that failed to be published storage.MaxFailedPublications times are skipped.
+func (s *Store) FindUnpublishedBooks(ctx context.Context, target, channel string) ([]*storage.Book, error) {
+	return s.FindBooks(ctx, sq.And{
+		sq.Expr(
+			"NOT EXISTS (SELECT 1 FROM publications p WHERE p.book_id = books.id AND p.target = ? AND p.channel IN (?, '') AND p.status = ?)",
+			target, channel, storage.PublicationPublished,
+		),
+		sq.Expr(
+			"(SELECT COUNT(*) FROM publications p WHERE p.book_id = books.id AND p.target = ? AND p.channel = ? AND p.status = ?) < ?",
+			target, channel, storage.PublicationFailed, storage.MaxFailedPublications,
+		),
+	})
+}
+
+// FindPublications returns publications of book, from the oldest to the newest.
+func (s *Store) FindPublications(ctx context.Context, bookID int64) ([]*storage.Publication, error) {
+	query, args, err := builder.
+		Select("id", "book_id", "target", "channel", "message_id", "with_image", "status", "published_at").
+		From("publications").
+		Where(sq.Eq{"book_id": bookID}).
+		OrderBy("published_at", "id").
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db(ctx).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("cannot find publications: %w", err)
+	}
+	defer rows.Close()
+
+	var publications []*storage.Publication
+	for rows.Next() {
+		var (
+			p         storage.Publication
+			messageID sql.NullString
+		)
+		if err := rows.Scan(&p.ID, &p.BookID, &p.Target, &p.Channel, &messageID, &p.WithImage, &p.Status, &p.PublishedAt); err != nil {
+			return nil, fmt.Errorf("cannot scan publication: %w", err)
+		}
+		p.MessageID = messageID.String
+
+		publications = append(publications, &p)
+	}
+
+	return publications, rows.Err()
+}
@@ -0,0 +1,82 @@
+package sqlite
+
+import (
+	"testing"
+
+	"github.com/tommsawyer/itbooks/storage"
+)
+
+func TestFindUnpublishedBooks(t *testing.T) {
+	ctx, is, store := openTestStore(t)
+
+	publishedID, err := store.UpsertBook(ctx, storage.UpsertBookParams{ISBN: "isbn", Title: "published"})
+	is.NoErr(err)
+
+	_, err = store.AddPublication(ctx, storage.AddPublicationParams{
+		BookID:    publishedID,
+		Target:    "telegram",
+		Channel:   "@first",
+		MessageID: "42",
+		WithImage: true,
+		Status:    storage.PublicationPublished,
+	})
+	is.NoErr(err)
+
+	failedID, err := store.UpsertBook(ctx, storage.UpsertBookParams{ISBN: "isbn2", Title: "failed"})
+	is.NoErr(err)
+
+	_, err = store.AddPublication(ctx, storage.AddPublicationParams{
+		BookID:  failedID,
+		Target:  "telegram",
+		Channel: "@first",
+		Status:  storage.PublicationFailed,
+	})
+	is.NoErr(err)
+
+	books, err := store.FindUnpublishedBooks(ctx, "telegram", "@first")
+	is.NoErr(err)
+	is.Equal(len(books), 1) // failed publication should be retried
+	is.Equal(books[0].ID, failedID)
+
+	books, err = store.FindUnpublishedBooks(ctx, "telegram", "@second")
+	is.NoErr(err)
+	is.Equal(len(books), 2) // books published to another channel are unpublished
+
+	publications, err := store.FindPublications(ctx, publishedID)
+	is.NoErr(err)
+	is.Equal(len(publications), 1)
+	is.Equal(publications[0].Channel, "@first")
+	is.Equal(publications[0].MessageID, "42")
+	is.True(publications[0].WithImage)
+	is.Equal(publications[0].Status, storage.PublicationPublished)
+	is.True(!publications[0].PublishedAt.IsZero())
+}
+
+func TestFindUnpublishedBooksSkipsBooksFailedTooManyTimes(t *testing.T) {
+	ctx, is, store := openTestStore(t)
+
+	id, err := store.UpsertBook(ctx, storage.UpsertBookParams{ISBN: "isbn", Title: "title"})
+	is.NoErr(err)
+
+	for i := 0; i < storage.MaxFailedPublications; i++ {
+		books, err := store.FindUnpublishedBooks(ctx, "telegram", "@channel")
+		is.NoErr(err)
+		is.Equal(len(books), 1) // book should be retried
+
+		_, err = store.AddPublication(ctx, storage.AddPublicationParams{
+			BookID:  id,
+			Target:  "telegram",
+			Channel: "@channel",
+			Status:  storage.PublicationFailed,
+		})
+		is.NoErr(err)
+	}
+
+	books, err := store.FindUnpublishedBooks(ctx, "telegram", "@channel")
+	is.NoErr(err)
+	is.Equal(len(books), 0)
+
+	books, err = store.FindUnpublishedBooks(ctx, "telegram", "@another")
+	is.NoErr(err)
+	is.Equal(len(books), 1) // failures in another channel don't count
+}
@@ -0,0 +1,317 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/tommsawyer/itbooks/isbn"
+	"github.com/tommsawyer/itbooks/storage"
+)
+
+// Store is in-memory storage.BookStore for tests that don't need real database.
+//
+// Filters are limited to squirrel.Eq and squirrel.And of them on columns id, isbn, url, title and publisher.
+// Only title and publisher fields can be updated.
+type Store struct {
+	mu           sync.Mutex
+	books        []*memoryBook
+	prices       []storage.BookPrice
+	publications []storage.Publication
+	nextID       int64
+}
+
+var _ storage.BookStore = (*Store)(nil)
+
+type memoryBook struct {
+	book storage.Book
+}
+
+// New creates empty in-memory store.
+func New() *Store {
+	return &Store{nextID: 1}
+}
+
+// UpsertBook implements storage.BookStore.
+func (m *Store) UpsertBook(_ context.Context, params storage.UpsertBookParams) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	row := m.findByISBN(params.ISBN)
+	if row == nil && !isbn.IsSynthetic(params.ISBN) && params.URL != "" {
+		// book stored under synthetic key gets real isbn
+		for _, b := range m.books {
+			if b.book.URL == params.URL && isbn.IsSynthetic(b.book.ISBN) {
+				row = b
+				break
+			}
+		}
+	}
+
+	now := time.Now().UTC()
+	if row == nil {
+		row = &memoryBook{book: storage.Book{
+			ID:        m.nextID,
+			CreatedAt: now,
+		}}
+		m.nextID++
+		m.books = append(m.books, row)
+	}
+
+	b := &row.book
+	b.ISBN = params.ISBN
+	b.URL = params.URL
+	b.Title = params.Title
+	b.Image = params.Image
+	b.Description = params.Description
+	b.Authors = params.Authors
+	b.Publisher = params.Publisher
+	b.Properties = params.Properties
+	b.ReleaseDate = params.ReleaseDate
+	b.Status = params.Status
+	if params.Price > 0 {
+		b.Price = params.Price
+		b.Currency = params.Currency
+	}
+	b.UpdatedAt = now
+
+	if params.Price > 0 {
+		m.prices = append(m.prices, storage.BookPrice{
+			ID:        int64(len(m.prices) + 1),
+			BookID:    b.ID,
+			Price:     params.Price,
+			Currency:  params.Currency,
+			CreatedAt: now,
+		})
+	}
+
+	return b.ID, nil
+}
+
+// UpdateBook implements storage.BookStore.
+func (m *Store) UpdateBook(_ context.Context, id int64, fields storage.Fields) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, row := range m.books {
+		if row.book.ID != id {
+			continue
+		}
+
+		for field, value := range fields {
+			s, ok := value.(string)
+			switch {
+			case field == "title" && ok:
+				row.book.Title = s
+			case field == "publisher" && ok:
+				row.book.Publisher = s
+			default:
+				return fmt.Errorf("cannot update %s of in-memory book", field)
+			}
+		}
+		row.book.UpdatedAt = time.Now().UTC()
+	}
+
+	return nil
+}
+
+// GetBook implements storage.BookStore.
+func (m *Store) GetBook(ctx context.Context, filter any) (*storage.Book, error) {
+	books, err := m.FindBooks(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(books) == 0 {
+		return nil, fmt.Errorf("cannot get book: %w", storage.ErrNotFound)
+	}
+
+	return books[0], nil
+}
+
+// FindBooks implements storage.BookStore.
+func (m *Store) FindBooks(_ context.Context, filter any) ([]*storage.Book, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var books []*storage.Book
+	for _, row := range m.books {
+		ok, err := row.match(filter)
+		if err != nil {
+			return nil, err
+		}
+
+		if ok {
+			book := row.book
+			books = append(books, &book)
+		}
+	}
+
+	return books, nil
+}
+
+// FindBookKeys implements storage.BookStore.
+func (m *Store) FindBookKeys(_ context.Context, updatedAfter time.Time) (urls []string, isbns []string, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, row := range m.books {
+		if !row.book.UpdatedAt.After(updatedAfter) {
+			continue
+		}
+
+		urls = append(urls, row.book.URL)
+		isbns = append(isbns, row.book.ISBN)
+	}
+
+	return urls, isbns, nil
+}
+
+// FindBookPrices implements storage.BookStore.
+func (m *Store) FindBookPrices(_ context.Context, key string) ([]*storage.BookPrice, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	row := m.findByISBN(key)
+	if row == nil {
+		return nil, nil
+	}
+
+	var prices []*storage.BookPrice
+	for _, price := range m.prices {
+		if price.BookID == row.book.ID {
+			price := price
+			prices = append(prices, &price)
+		}
+	}
+
+	return prices, nil
+}
+
+// AddPublication implements storage.BookStore.
+func (m *Store) AddPublication(_ context.Context, params storage.AddPublicationParams) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	publication := storage.Publication{
+		ID:          int64(len(m.publications) + 1),
+		BookID:      params.BookID,
+		Target:      params.Target,
+		Channel:     params.Channel,
+		MessageID:   params.MessageID,
+		WithImage:   params.WithImage,
+		Status:      params.Status,
+		PublishedAt: time.Now().UTC(),
+	}
+	m.publications = append(m.publications, publication)
+
+	return publication.ID, nil
+}
+
+// FindUnpublishedBooks implements storage.BookStore.
+func (m *Store) FindUnpublishedBooks(ctx context.Context, target, channel string) ([]*storage.Book, error) {
+	books, err := m.FindBooks(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var unpublished []*storage.Book
+	for _, book := range books {
+		if !m.published(book.ID, target, channel) && m.failed(book.ID, target, channel) < storage.MaxFailedPublications {
+			unpublished = append(unpublished, book)
+		}
+	}
+
+	return unpublished, nil
+}
+
+// FindPublications implements storage.BookStore.
+func (m *Store) FindPublications(_ context.Context, bookID int64) ([]*storage.Publication, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var publications []*storage.Publication
+	for _, publication := range m.publications {
+		if publication.BookID == bookID {
+			publication := publication
+			publications = append(publications, &publication)
+		}
+	}
+
+	return publications, nil
+}
+
+func (m *Store) published(bookID int64, target, channel string) bool {
+	for _, p := range m.publications {
+		if p.BookID == bookID && p.Target == target && (p.Channel == channel || p.Channel == "") && p.Status == storage.PublicationPublished {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (m *Store) failed(bookID int64, target, channel string) int {
+	var failed int
+	for _, p := range m.publications {
+		if p.BookID == bookID && p.Target == target && p.Channel == channel && p.Status == storage.PublicationFailed {
+			failed++
+		}
+	}
+
+	return failed
+}
+
+func (m *Store) findByISBN(key string) *memoryBook {
+	for _, row := range m.books {
+		if row.book.ISBN == key {
+			return row
+		}
+	}
+
+	return nil
+}
+
+func (b *memoryBook) match(filter any) (bool, error) {
+	switch f := filter.(type) {
+	case nil:
+		return true, nil
+	case sq.And:
+		for _, filter := range f {
+			if ok, err := b.match(filter); !ok || err != nil {
+				return false, err
+			}
+		}
+		return true, nil
+	case sq.Eq:
+		for column, expected := range f {
+			var actual any
+			switch column {
+			case "id":
+				actual = b.book.ID
+			case "isbn":
+				actual = b.book.ISBN
+			case "url":
+				actual = b.book.URL
+			case "title":
+				actual = b.book.Title
+			case "publisher":
+				actual = b.book.Publisher
+			default:
+				return false, fmt.Errorf("cannot filter in-memory books by %s", column)
+			}
+
+			if fmt.Sprint(actual) != fmt.Sprint(expected) {
+				return false, nil
+			}
+		}
+		return true, nil
+	default:
+		return false, fmt.Errorf("cannot filter in-memory books by %T", filter)
+	}
+}
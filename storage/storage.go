@@ -0,0 +1,133 @@
+// Package storage declares books store and its types, so commands work the same way
+// with any database, see packages postgres and sqlite.
+package storage
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrNotFound is returned when requested book doesn't exist.
+var ErrNotFound = errors.New("not found")
+
+// Fields is used for update queries.
+type Fields = map[string]any
+
+// BookStore stores books. It's implemented by postgres, by SQLite
+// and by in-memory store from storage/memory, so code using it can be tested without database.
+//
+// Filters are squirrel expressions, e.g. squirrel.Eq{"isbn": isbn}.
+type BookStore interface {
+	UpsertBook(ctx context.Context, params UpsertBookParams) (int64, error)
+	UpdateBook(ctx context.Context, id int64, fields Fields) error
+	GetBook(ctx context.Context, filter any) (*Book, error)
+	FindBooks(ctx context.Context, filter any) ([]*Book, error)
+	FindBookKeys(ctx context.Context, updatedAfter time.Time) (urls []string, isbns []string, err error)
+	FindBookPrices(ctx context.Context, isbn string) ([]*BookPrice, error)
+	AddPublication(ctx context.Context, params AddPublicationParams) (int64, error)
+	FindUnpublishedBooks(ctx context.Context, target, channel string) ([]*Book, error)
+	FindPublications(ctx context.Context, bookID int64) ([]*Publication, error)
+}
+
+// Book is stored book.
+type Book struct {
+	ID          int64
+	ISBN        string
+	URL         string
+	Title       string
+	Image       string
+	Description string
+	Authors     []string
+	Publisher   string
+	Properties  map[string]string
+	// ReleaseDate is zero if unknown.
+	ReleaseDate time.Time
+	// Status is one of "preorder", "in_stock" and "out_of_print", empty if unknown.
+	Status string
+	// Price is zero if unknown.
+	Price     float64
+	Currency  string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// Upcoming reports whether book isn't released yet at given time.
+func (b *Book) Upcoming(now time.Time) bool {
+	return b.Status == "preorder" || (!b.ReleaseDate.IsZero() && b.ReleaseDate.After(now))
+}
+
+// UpsertBookParams is parameters required for inserting book.
+type UpsertBookParams struct {
+	ISBN        string
+	URL         string
+	Title       string
+	Image       string
+	Description string
+	// Authors are also linked to book in authors table of postgres, see postgres.Store.FindBooksByAuthor.
+	Authors []string
+	// Publisher is also stored in publishers table of postgres, see postgres.Store.FindBooksByPublisher.
+	Publisher  string
+	Properties map[string]string
+	// ReleaseDate is stored as NULL if zero.
+	ReleaseDate time.Time
+	// Status is one of "preorder", "in_stock" and "out_of_print".
+	// It's stored as NULL if empty.
+	Status string
+	// Price is zero if unknown, then previously stored price is kept.
+	// Every non-zero price is added to price history.
+	Price    float64
+	Currency string
+}
+
+// BookPrice is price of book at some moment, see BookStore.FindBookPrices.
+type BookPrice struct {
+	ID        int64
+	BookID    int64
+	Price     float64
+	Currency  string
+	CreatedAt time.Time
+}
+
+// Statuses of publications.
+const (
+	// PublicationPublished is status of book announced to channel.
+	PublicationPublished = "published"
+	// PublicationFailed is status of failed attempt to announce book.
+	// Such books are still unpublished.
+	PublicationFailed = "failed"
+)
+
+// MaxFailedPublications is number of failed attempts to publish book to channel
+// after which book isn't selected anymore, so book that can't be published doesn't block others.
+const MaxFailedPublications = 3
+
+// Publication is an attempt to announce book to channel of target, e.g. telegram.
+type Publication struct {
+	ID      int64
+	BookID  int64
+	Target  string
+	Channel string
+	// MessageID is id of message in channel, empty if target didn't return it.
+	MessageID string
+	// WithImage is set if message was sent with image, so its caption should be edited instead of text.
+	// Publications recorded before it was tracked are assumed to have image.
+	WithImage   bool
+	Status      string
+	PublishedAt time.Time
+	// EditedAt is time of the last edit of message, zero if it wasn't edited.
+	EditedAt time.Time
+}
+
+// AddPublicationParams is parameters required for adding publication.
+type AddPublicationParams struct {
+	BookID  int64
+	Target  string
+	Channel string
+	// MessageID is stored as NULL if empty.
+	MessageID string
+	// WithImage is set if message was sent with image.
+	WithImage bool
+	// Status is PublicationPublished or PublicationFailed.
+	Status string
+}
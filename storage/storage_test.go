@@ -0,0 +1,28 @@
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/matryer/is"
+)
+
+func TestBookUpcoming(t *testing.T) {
+	is := is.New(t)
+	now := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	preorder := Book{Status: "preorder"}
+	is.True(preorder.Upcoming(now))
+
+	future := Book{ReleaseDate: now.AddDate(0, 1, 0)}
+	is.True(future.Upcoming(now))
+
+	released := Book{
+		ReleaseDate: now.AddDate(0, -1, 0),
+		Status:      "in_stock",
+	}
+	is.True(!released.Upcoming(now))
+
+	unknown := Book{}
+	is.True(!unknown.Upcoming(now))
+}
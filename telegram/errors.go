@@ -0,0 +1,144 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Permanent errors, retrying request won't help.
+// Use errors.Is to check them, e.g. errors.Is(err, telegram.ErrChatNotFound).
+var (
+	// ErrChatNotFound is returned when channel doesn't exist.
+	ErrChatNotFound = errors.New("chat not found")
+	// ErrBotKicked is returned when bot isn't a member or administrator of channel.
+	ErrBotKicked = errors.New("bot is kicked from chat")
+	// ErrBadImage is returned when telegram can't download or process image of message.
+	ErrBadImage = errors.New("bad image")
+	// ErrNoCaption is returned when caption of message sent without image is edited.
+	ErrNoCaption = errors.New("no caption in the message")
+	// ErrNoMedia is returned when image of message sent without image is edited.
+	ErrNoMedia = errors.New("no media in the message")
+)
+
+// Error is error returned by telegram api.
+type Error struct {
+	// Code is HTTP status code of response.
+	Code        int
+	Description string
+	// RetryAfter is time to wait before the next request if rate limit is exceeded.
+	RetryAfter time.Duration
+	// kind is one of permanent errors or nil.
+	kind error
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("telegram api error %d: %s", e.Code, e.Description)
+}
+
+// Unwrap returns permanent error matching description of e, if any.
+func (e *Error) Unwrap() error {
+	return e.kind
+}
+
+// Temporary reports whether request may succeed if retried,
+// e.g. when rate limit is exceeded or telegram is unavailable.
+func (e *Error) Temporary() bool {
+	return e.Code == http.StatusTooManyRequests || e.Code >= http.StatusInternalServerError
+}
+
+// badImageDescriptions are parts of descriptions of errors returned when image of message is unusable.
+var badImageDescriptions = []string{
+	"wrong file identifier/http url specified",
+	"failed to get http url content",
+	"wrong type of the web page content",
+	"image_process_failed",
+	"photo_invalid_dimensions",
+	"photo_save_file_invalid",
+}
+
+// apiError converts error of tgbotapi to *Error, other errors are returned as is.
+func apiError(err error) error {
+	var tgErr *tgbotapi.Error
+	if !errors.As(err, &tgErr) {
+		return err
+	}
+
+	e := &Error{
+		Code:        tgErr.Code,
+		Description: tgErr.Message,
+		RetryAfter:  time.Duration(tgErr.RetryAfter) * time.Second,
+	}
+
+	description := strings.ToLower(tgErr.Message)
+	switch {
+	case strings.Contains(description, "chat not found"):
+		e.kind = ErrChatNotFound
+	case tgErr.Code == http.StatusForbidden:
+		e.kind = ErrBotKicked
+	case strings.Contains(description, "no caption in the message"):
+		e.kind = ErrNoCaption
+	case strings.Contains(description, "no media in the message"):
+		e.kind = ErrNoMedia
+	default:
+		for _, part := range badImageDescriptions {
+			if strings.Contains(description, part) {
+				e.kind = ErrBadImage
+				break
+			}
+		}
+	}
+
+	return e
+}
+
+// Retry policy of requests to telegram.
+var (
+	maxAttempts = 5
+	// minBackoff is delay before the second attempt, it's doubled for every next attempt.
+	minBackoff = time.Second
+	maxBackoff = 30 * time.Second
+)
+
+// retry calls request until it succeeds, fails permanently or attempts are exhausted.
+// Network errors are retried too, so message may be sent twice if response was lost.
+func retry(ctx context.Context, request func() error) error {
+	backoff := minBackoff
+
+	for attempt := 1; ; attempt++ {
+		err := apiError(request())
+		if err == nil {
+			return nil
+		}
+
+		var apiErr *Error
+		if errors.As(err, &apiErr) && !apiErr.Temporary() {
+			return err
+		}
+
+		if attempt == maxAttempts {
+			return fmt.Errorf("%d attempts failed: %w", attempt, err)
+		}
+
+		delay := backoff
+		if apiErr != nil && apiErr.RetryAfter > 0 {
+			delay = apiErr.RetryAfter
+		}
+		if backoff *= 2; backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("%w, last error: %v", ctx.Err(), err)
+		case <-timer.C:
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/matryer/is"
+)
+
+func TestAPIErrorClassifiesPermanentErrors(t *testing.T) {
+	is := is.New(t)
+
+	err := apiError(&tgbotapi.Error{Code: 400, Message: "Bad Request: chat not found"})
+	is.True(errors.Is(err, ErrChatNotFound))
+
+	err = apiError(&tgbotapi.Error{Code: 403, Message: "Forbidden: bot was kicked from the channel chat"})
+	is.True(errors.Is(err, ErrBotKicked))
+
+	err = apiError(&tgbotapi.Error{Code: 400, Message: "Bad Request: wrong type of the web page content"})
+	is.True(errors.Is(err, ErrBadImage))
+
+	err = apiError(&tgbotapi.Error{Code: 400, Message: "Bad Request: there is no caption in the message to edit"})
+	is.True(errors.Is(err, ErrNoCaption))
+
+	err = apiError(&tgbotapi.Error{Code: 400, Message: "Bad Request: there is no media in the message to edit"})
+	is.True(errors.Is(err, ErrNoMedia))
+
+	err = apiError(&tgbotapi.Error{Code: 400, Message: "Bad Request: can't parse entities"})
+	var apiErr *Error
+	is.True(errors.As(err, &apiErr))
+	is.True(!apiErr.Temporary())
+	is.True(!errors.Is(err, ErrBadImage))
+}
+
+func TestAPIErrorKeepsRetryAfter(t *testing.T) {
+	is := is.New(t)
+
+	err := apiError(&tgbotapi.Error{
+		Code:               429,
+		Message:            "Too Many Requests: retry after 5",
+		ResponseParameters: tgbotapi.ResponseParameters{RetryAfter: 5},
+	})
+
+	var apiErr *Error
+	is.True(errors.As(err, &apiErr))
+	is.True(apiErr.Temporary())
+	is.Equal(apiErr.RetryAfter, 5*time.Second)
+}
+
+func withFastRetries(t *testing.T) {
+	oldMin, oldMax := minBackoff, maxBackoff
+	minBackoff, maxBackoff = time.Millisecond, time.Millisecond
+	t.Cleanup(func() { minBackoff, maxBackoff = oldMin, oldMax })
+}
+
+func TestRetryRetriesTemporaryErrors(t *testing.T) {
+	is := is.New(t)
+	withFastRetries(t)
+
+	var attempts int
+	err := retry(context.Background(), func() error {
+		attempts++
+		switch attempts {
+		case 1:
+			return errors.New("connection reset by peer")
+		case 2:
+			return &tgbotapi.Error{Code: 502, Message: "Bad Gateway"}
+		default:
+			return nil
+		}
+	})
+
+	is.NoErr(err)
+	is.Equal(attempts, 3)
+}
+
+func TestRetryStopsOnPermanentErrors(t *testing.T) {
+	is := is.New(t)
+	withFastRetries(t)
+
+	var attempts int
+	err := retry(context.Background(), func() error {
+		attempts++
+		return &tgbotapi.Error{Code: 400, Message: "Bad Request: chat not found"}
+	})
+
+	is.True(errors.Is(err, ErrChatNotFound))
+	is.Equal(attempts, 1) // permanent errors shouldn't be retried
+}
+
+func TestRetryGivesUp(t *testing.T) {
+	is := is.New(t)
+	withFastRetries(t)
+
+	var attempts int
+	err := retry(context.Background(), func() error {
+		attempts++
+		return &tgbotapi.Error{Code: 500, Message: "Internal Server Error"}
+	})
+
+	var apiErr *Error
+	is.True(errors.As(err, &apiErr))
+	is.Equal(apiErr.Code, 500)
+	is.Equal(attempts, maxAttempts)
+}
+
+func TestRetryStopsWhenContextIsDone(t *testing.T) {
+	is := is.New(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var attempts int
+	err := retry(ctx, func() error {
+		attempts++
+		cancel()
+		return &tgbotapi.Error{
+			Code:               429,
+			Message:            "Too Many Requests: retry after 60",
+			ResponseParameters: tgbotapi.ResponseParameters{RetryAfter: 60},
+		}
+	})
+
+	is.True(errors.Is(err, context.Canceled))
+	is.Equal(attempts, 1)
+}
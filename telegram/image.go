@@ -0,0 +1,117 @@
+package telegram
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif" // gif covers
+	"image/jpeg"
+	_ "image/png" // png covers
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp" // webp covers
+)
+
+const (
+	// maxImageDownloadSize is size of the largest cover that is downloaded.
+	maxImageDownloadSize = 20 << 20
+	// maxImageSide is the longest side of uploaded cover, telegram shows photos no larger than that anyway.
+	maxImageSide = 1280
+	// minImageSide is the shortest side of usable cover, smaller images are placeholders or tracking pixels.
+	minImageSide = 32
+	// maxImageRatio is the maximum ratio of cover sides accepted by telegram.
+	maxImageRatio = 20
+	jpegQuality   = 90
+)
+
+// errNoImage is returned when message has no image URL.
+var errNoImage = errors.New("no image")
+
+var imageClient = &http.Client{Timeout: 30 * time.Second}
+
+// downloadImage downloads cover from imageURL and converts it to JPEG acceptable by telegram.
+// Image is uploaded to telegram instead of passing URL, since telegram can't fetch WebP,
+// huge or hotlink-protected covers by itself.
+func downloadImage(ctx context.Context, imageURL string) ([]byte, error) {
+	if imageURL == "" {
+		return nil, errNoImage
+	}
+
+	u, err := url.Parse(imageURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid image url: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "itbooks (+https://github.com/tommsawyer/itbooks)")
+	// hotlink protection allows images to be loaded by pages of the same site
+	req.Header.Set("Referer", u.Scheme+"://"+u.Host+"/")
+
+	resp, err := imageClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("cannot download image: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("cannot download image: unexpected status %s", resp.Status)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxImageDownloadSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("cannot download image: %w", err)
+	}
+	if len(data) > maxImageDownloadSize {
+		return nil, fmt.Errorf("image is larger than %d bytes", maxImageDownloadSize)
+	}
+
+	return convertImage(data)
+}
+
+// convertImage decodes JPEG, PNG, GIF or WebP image and encodes it as JPEG,
+// downscaling it to fit into maxImageSide.
+func convertImage(data []byte) ([]byte, error) {
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("cannot decode image: %w", err)
+	}
+
+	width, height := src.Bounds().Dx(), src.Bounds().Dy()
+	if width < minImageSide || height < minImageSide {
+		return nil, fmt.Errorf("image %dx%d is too small", width, height)
+	}
+	if width > maxImageRatio*height || height > maxImageRatio*width {
+		return nil, fmt.Errorf("image %dx%d has too large ratio", width, height)
+	}
+
+	longest := width
+	if height > longest {
+		longest = height
+	}
+	if longest > maxImageSide {
+		width = width * maxImageSide / longest
+		height = height * maxImageSide / longest
+	}
+
+	// JPEG has no transparency, so transparent parts of image are painted white instead of black
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(dst, dst.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, src.Bounds(), draw.Over, nil)
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, dst, &jpeg.Options{Quality: jpegQuality}); err != nil {
+		return nil, fmt.Errorf("cannot encode image: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,116 @@
+package telegram
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/matryer/is"
+)
+
+func encodePNG(t *testing.T, width, height int) []byte {
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for x := 0; x < width; x++ {
+		for y := 0; y < height; y++ {
+			img.Set(x, y, color.NRGBA{R: 255, A: 128})
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.Bytes()
+}
+
+func TestDownloadImageConvertsToJPEG(t *testing.T) {
+	is := is.New(t)
+
+	cover := encodePNG(t, 300, 400)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Referer() == "" {
+			w.WriteHeader(http.StatusForbidden) // hotlink protection
+			return
+		}
+
+		w.Header().Set("Content-Type", "image/png")
+		_, _ = w.Write(cover)
+	}))
+	defer server.Close()
+
+	data, err := downloadImage(context.Background(), server.URL+"/cover.png")
+	is.NoErr(err)
+
+	img, err := jpeg.Decode(bytes.NewReader(data))
+	is.NoErr(err) // cover should be converted to jpeg
+	is.Equal(img.Bounds().Dx(), 300)
+	is.Equal(img.Bounds().Dy(), 400)
+}
+
+func TestDownloadImageFailures(t *testing.T) {
+	is := is.New(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/html":
+			_, _ = w.Write([]byte("<html></html>"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	_, err := downloadImage(context.Background(), "")
+	is.True(errors.Is(err, errNoImage))
+
+	_, err = downloadImage(context.Background(), server.URL+"/html")
+	is.True(err != nil) // not an image
+
+	_, err = downloadImage(context.Background(), server.URL+"/missing")
+	is.True(err != nil)
+}
+
+func TestConvertImageDownscalesLargeImages(t *testing.T) {
+	is := is.New(t)
+
+	data, err := convertImage(encodePNG(t, 2*maxImageSide, maxImageSide))
+	is.NoErr(err)
+
+	img, err := jpeg.Decode(bytes.NewReader(data))
+	is.NoErr(err)
+	is.Equal(img.Bounds().Dx(), maxImageSide)
+	is.Equal(img.Bounds().Dy(), maxImageSide/2)
+}
+
+func TestConvertImagePaintsTransparencyWhite(t *testing.T) {
+	is := is.New(t)
+
+	data, err := convertImage(encodePNG(t, 100, 100))
+	is.NoErr(err)
+
+	img, err := jpeg.Decode(bytes.NewReader(data))
+	is.NoErr(err)
+
+	r, g, b, _ := img.At(50, 50).RGBA()
+	// half transparent red over white is pink, over black it would be dark red
+	is.True(r>>8 > 240)
+	is.True(g>>8 > 100 && b>>8 > 100)
+}
+
+func TestConvertImageRejectsUnusableImages(t *testing.T) {
+	is := is.New(t)
+
+	_, err := convertImage(encodePNG(t, 1, 1))
+	is.True(err != nil) // tracking pixel
+
+	_, err = convertImage(encodePNG(t, 2000, 40))
+	is.True(err != nil) // too large ratio
+}
@@ -2,14 +2,24 @@ package telegram
 
 import (
 	"embed"
+	"math"
+	"strconv"
 	"strings"
 	"text/template"
+	"time"
 	"unicode/utf8"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 )
 
-const maxTelegramMessageSize = 1024
+// Limits of telegram on length of messages.
+const (
+	maxCaptionSize = 1024
+	maxTextSize    = 4096
+)
+
+// ellipsis ends part of description continued in the next message.
+const ellipsis = "…"
 
 var (
 	//go:embed templates/*
@@ -18,9 +28,11 @@ var (
 	messageTemplates = template.Must(
 		template.New("message").
 			Funcs(template.FuncMap{
-				"escape": func(s string) string {
-					return tgbotapi.EscapeText(tgbotapi.ModeMarkdownV2, s)
+				"escape": escape,
+				"date": func(t time.Time) string {
+					return t.Format("02.01.2006")
 				},
+				"price": formatPrice,
 			}).
 			ParseFS(templatesDir, "templates/*.md"),
 	)
@@ -33,40 +45,269 @@ type Message struct {
 	Subtitle string
 	Link     string
 	Text     string
+	// Upcoming books are announced as preorders.
+	Upcoming bool
+	// ReleaseDate is shown for upcoming books if not zero.
+	ReleaseDate time.Time
+	// Price is shown if not zero.
+	Price float64
+	// Currency is ISO 4217 code of price currency.
+	Currency string
 }
 
-func (msg *Message) imageWithCaption(channel string) (tgbotapi.PhotoConfig, error) {
-	text, err := msg.markdown()
+func (msg *Message) imageWithCaption(channel string, image tgbotapi.RequestFileData) (tgbotapi.PhotoConfig, error) {
+	text, _, err := msg.markdown(maxCaptionSize)
 	if err != nil {
 		return tgbotapi.PhotoConfig{}, err
 	}
 
-	upload := tgbotapi.NewPhotoToChannel(
-		channel,
-		tgbotapi.FileURL(msg.ImageURL),
-	)
+	upload := tgbotapi.NewPhotoToChannel(channel, image)
 	upload.Caption = text
 	upload.ParseMode = tgbotapi.ModeMarkdownV2
 
 	return upload, nil
 }
 
-func (msg *Message) markdown() (string, error) {
+// textOnly is message without image, it's sent when book has no usable cover.
+func (msg *Message) textOnly(channel string) (tgbotapi.MessageConfig, error) {
+	text, _, err := msg.markdown(maxTextSize)
+	if err != nil {
+		return tgbotapi.MessageConfig{}, err
+	}
+
+	message := tgbotapi.NewMessageToChannel(channel, text)
+	message.ParseMode = tgbotapi.ModeMarkdownV2
+
+	return message, nil
+}
+
+func (msg *Message) editCaption(channel string, messageID int) (tgbotapi.EditMessageCaptionConfig, error) {
+	text, _, err := msg.markdown(maxCaptionSize)
+	if err != nil {
+		return tgbotapi.EditMessageCaptionConfig{}, err
+	}
+
+	return tgbotapi.EditMessageCaptionConfig{
+		BaseEdit: tgbotapi.BaseEdit{
+			ChannelUsername: channel,
+			MessageID:       messageID,
+		},
+		Caption:   text,
+		ParseMode: tgbotapi.ModeMarkdownV2,
+	}, nil
+}
+
+// editText edits text of message sent by textOnly.
+func (msg *Message) editText(channel string, messageID int) (tgbotapi.EditMessageTextConfig, error) {
+	text, _, err := msg.markdown(maxTextSize)
+	if err != nil {
+		return tgbotapi.EditMessageTextConfig{}, err
+	}
+
+	return tgbotapi.EditMessageTextConfig{
+		BaseEdit: tgbotapi.BaseEdit{
+			ChannelUsername: channel,
+			MessageID:       messageID,
+		},
+		Text:      text,
+		ParseMode: tgbotapi.ModeMarkdownV2,
+	}, nil
+}
+
+func (msg *Message) editMedia(channel string, messageID int, image tgbotapi.RequestFileData) (tgbotapi.EditMessageMediaConfig, error) {
+	text, _, err := msg.markdown(maxCaptionSize)
+	if err != nil {
+		return tgbotapi.EditMessageMediaConfig{}, err
+	}
+
+	photo := tgbotapi.NewInputMediaPhoto(image)
+	photo.Caption = text
+	photo.ParseMode = tgbotapi.ModeMarkdownV2
+
+	return tgbotapi.EditMessageMediaConfig{
+		BaseEdit: tgbotapi.BaseEdit{
+			ChannelUsername: channel,
+			MessageID:       messageID,
+		},
+		Media: photo,
+	}, nil
+}
+
+// overflow is reply to message sent with given limit, it contains the rest of description.
+// It returns false if description fits into message and no reply is needed.
+func (msg *Message) overflow(channel string, replyTo int, limit int) (tgbotapi.MessageConfig, bool, error) {
+	_, rest, err := msg.markdown(limit)
+	if err != nil || rest == "" {
+		return tgbotapi.MessageConfig{}, false, err
+	}
+
+	text, tail := splitText(rest, maxTextSize-utf8.RuneCountInString(ellipsis))
+	if tail != "" {
+		text += ellipsis
+	}
+
+	reply := tgbotapi.NewMessageToChannel(channel, escape(text))
+	reply.ParseMode = tgbotapi.ModeMarkdownV2
+	reply.ReplyToMessageID = replyTo
+	reply.DisableWebPagePreview = true
+
+	return reply, true, nil
+}
+
+// markdown renders message no longer than limit runes.
+// If description doesn't fit, it's cut at paragraph, sentence or word boundary and the rest is returned.
+// If even title and subtitle don't fit, they are shortened and the whole description is returned as the rest.
+func (msg *Message) markdown(limit int) (text string, rest string, err error) {
+	text, err = msg.render()
+	if err != nil || utf8.RuneCountInString(text) <= limit {
+		return text, "", err
+	}
+
+	withoutText := *msg
+	withoutText.Text = ""
+	header, err := withoutText.render()
+	if err != nil {
+		return "", "", err
+	}
+
+	size := limit - utf8.RuneCountInString(header) - utf8.RuneCountInString(ellipsis)
+	if size <= 0 {
+		text, err = msg.shortHeader(limit)
+		return text, msg.Text, err
+	}
+
+	head, rest := splitText(msg.Text, size)
+	if head != "" {
+		head += ellipsis
+	}
+
+	cut := *msg
+	cut.Text = head
+	text, err = cut.render()
+
+	return text, rest, err
+}
+
+// shortHeader renders message without description, title and subtitle are shortened to fit into limit runes.
+// Title is kept whole if possible, since it's more important than subtitle.
+func (msg *Message) shortHeader(limit int) (string, error) {
+	short := *msg
+	short.Title, short.Subtitle, short.Text = "", "", ""
+	frame, err := short.render()
+	if err != nil {
+		return "", err
+	}
+
+	size := limit - utf8.RuneCountInString(frame)
+	short.Title = shorten(msg.Title, size)
+	short.Subtitle = shorten(msg.Subtitle, size-utf8.RuneCountInString(escape(short.Title)))
+
+	return short.render()
+}
+
+func (msg *Message) render() (string, error) {
 	var builder strings.Builder
 
 	if err := messageTemplates.ExecuteTemplate(&builder, "book.md", msg); err != nil {
 		return "", err
 	}
 
-	return msg.truncate(builder.String()), nil
+	return builder.String(), nil
+}
+
+func escape(s string) string {
+	return tgbotapi.EscapeText(tgbotapi.ModeMarkdownV2, s)
 }
 
-func (msg *Message) truncate(s string) string {
-	if utf8.RuneCountInString(s) <= maxTelegramMessageSize {
+// splitText splits text into head that is no longer than limit runes after escaping and the rest.
+//
+// Text is split at the last paragraph, sentence or word boundary in the second half of head,
+// in this order of preference. Text without such boundaries is cut at limit, but never inside
+// escape sequence, since text is escaped after splitting.
+func splitText(text string, limit int) (head, rest string) {
+	// cut is byte offset of the longest prefix fitting into limit
+	cut, size := 0, 0
+	for i, r := range text {
+		size += utf8.RuneCountInString(escape(string(r)))
+		if size > limit {
+			break
+		}
+		cut = i + utf8.RuneLen(r)
+	}
+
+	if cut == len(text) {
+		return text, ""
+	}
+
+	boundary := cut
+	for _, find := range []func(s string) int{lastParagraphEnd, lastSentenceEnd, lastWordEnd} {
+		if i := find(text[:cut]); i > cut/2 {
+			boundary = i
+			break
+		}
+	}
+
+	return strings.TrimSpace(text[:boundary]), strings.TrimSpace(text[boundary:])
+}
+
+// shorten cuts s at word boundary and appends ellipsis, so it's no longer than limit runes after escaping.
+func shorten(s string, limit int) string {
+	if utf8.RuneCountInString(escape(s)) <= limit {
 		return s
 	}
 
-	dots := tgbotapi.EscapeText(tgbotapi.ModeMarkdownV2, "...")
+	if limit <= utf8.RuneCountInString(ellipsis) {
+		return ""
+	}
+
+	head, _ := splitText(s, limit-utf8.RuneCountInString(ellipsis))
+	return head + ellipsis
+}
+
+// lastParagraphEnd returns offset of the last line break in s or -1.
+func lastParagraphEnd(s string) int {
+	return strings.LastIndex(s, "\n")
+}
+
+// lastSentenceEnd returns offset after the last sentence ending punctuation followed by space in s or -1.
+func lastSentenceEnd(s string) int {
+	end := -1
+	for i, r := range s {
+		if !strings.ContainsRune(".!?…", r) {
+			continue
+		}
+
+		next := i + utf8.RuneLen(r)
+		if next < len(s) && (s[next] == ' ' || s[next] == '\n') {
+			end = next
+		}
+	}
+
+	return end
+}
+
+// lastWordEnd returns offset of the last space in s or -1.
+func lastWordEnd(s string) int {
+	return strings.LastIndexAny(s, " \t\n")
+}
+
+var currencySymbols = map[string]string{
+	"RUB": "₽",
+	"USD": "$",
+	"EUR": "€",
+	"GBP": "£",
+}
+
+// formatPrice formats price like "1299 ₽" or "39.99 $".
+func formatPrice(price float64, currency string) string {
+	amount := strconv.FormatFloat(price, 'f', 2, 64)
+	if price == math.Trunc(price) {
+		amount = strconv.FormatFloat(price, 'f', 0, 64)
+	}
+
+	if symbol, ok := currencySymbols[currency]; ok {
+		currency = symbol
+	}
 
-	return string([]rune(s)[:maxTelegramMessageSize-utf8.RuneCountInString(dots)]) + dots
+	return strings.TrimSpace(amount + " " + currency)
 }
@@ -3,6 +3,7 @@ package telegram
 import (
 	"strings"
 	"testing"
+	"time"
 	"unicode/utf8"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
@@ -20,7 +21,7 @@ func TestMessageBuildedCorrectly(t *testing.T) {
 		Text:     "text",
 	}
 
-	upload, err := msg.imageWithCaption("channel")
+	upload, err := msg.imageWithCaption("channel", tgbotapi.FileURL("imageurl"))
 	is.NoErr(err)
 
 	is.Equal(upload.BaseChat.ChannelUsername, "channel")
@@ -38,6 +39,71 @@ text
 	is.Equal(strings.TrimSpace(upload.Caption), strings.TrimSpace(expectedText))
 }
 
+func TestMessageAnnouncesUpcomingBook(t *testing.T) {
+	is := is.New(t)
+
+	msg := Message{
+		ImageURL:    "imageurl",
+		Title:       "title",
+		Subtitle:    "subtitle",
+		Link:        "link",
+		Text:        "text",
+		Upcoming:    true,
+		ReleaseDate: time.Date(2024, 3, 12, 0, 0, 0, 0, time.UTC),
+	}
+
+	upload, err := msg.imageWithCaption("channel", tgbotapi.FileURL("imageurl"))
+	is.NoErr(err)
+
+	expectedText := `
+*title*
+_subtitle_
+Скоро выйдет: 12\.03\.2024
+[Предзаказ](link)
+
+text
+`
+
+	is.Equal(strings.TrimSpace(upload.Caption), strings.TrimSpace(expectedText))
+}
+
+func TestMessageShowsPrice(t *testing.T) {
+	is := is.New(t)
+
+	msg := Message{
+		ImageURL: "imageurl",
+		Title:    "title",
+		Subtitle: "subtitle",
+		Link:     "link",
+		Text:     "text",
+		Price:    1050.5,
+		Currency: "RUB",
+	}
+
+	upload, err := msg.imageWithCaption("channel", tgbotapi.FileURL("imageurl"))
+	is.NoErr(err)
+
+	expectedText := `
+*title*
+_subtitle_
+Цена: 1050\.50 ₽
+[Купить](link)
+
+text
+`
+
+	is.Equal(strings.TrimSpace(upload.Caption), strings.TrimSpace(expectedText))
+}
+
+func TestFormatPrice(t *testing.T) {
+	is := is.New(t)
+
+	is.Equal(formatPrice(1299, "RUB"), "1299 ₽")
+	is.Equal(formatPrice(39.99, "USD"), "39.99 $")
+	is.Equal(formatPrice(100, "KZT"), "100 KZT")
+	is.Equal(formatPrice(100, ""), "100")
+}
+
 func TestMessageTruncateTooLongTexts(t *testing.T) {
 	is := is.New(t)
 
@@ -49,8 +115,182 @@ func TestMessageTruncateTooLongTexts(t *testing.T) {
 		Text:     strings.Repeat("test", 1000),
 	}
 
-	upload, err := msg.imageWithCaption("channel")
+	upload, err := msg.imageWithCaption("channel", tgbotapi.FileURL("imageurl"))
+	is.NoErr(err)
+
+	is.True(utf8.RuneCountInString(upload.Caption) <= maxCaptionSize)
+	is.True(strings.HasSuffix(strings.TrimSpace(upload.Caption), ellipsis))
+
+	reply, ok, err := msg.overflow("channel", 42, maxCaptionSize)
+	is.NoErr(err)
+	is.True(ok) // the rest of description should be sent as reply
+	is.Equal(reply.ReplyToMessageID, 42)
+	is.Equal(reply.ParseMode, tgbotapi.ModeMarkdownV2)
+
+	captionText := strings.TrimSuffix(strings.TrimSpace(upload.Caption), ellipsis)
+	captionText = captionText[strings.LastIndex(captionText, "\n")+1:]
+	is.Equal(captionText+reply.Text, msg.Text) // nothing should be lost
+}
+
+func TestMessageSplitsLongDescriptionAtSentence(t *testing.T) {
+	is := is.New(t)
+
+	sentence := "Книга о Go (версия 1.22)! "
+	msg := Message{
+		ImageURL: "imageurl",
+		Title:    "title",
+		Subtitle: "subtitle",
+		Link:     "link",
+		Text:     strings.Repeat(sentence, 100),
+	}
+
+	upload, err := msg.imageWithCaption("channel", tgbotapi.FileURL("imageurl"))
+	is.NoErr(err)
+
+	caption := strings.TrimSpace(upload.Caption)
+	is.True(utf8.RuneCountInString(caption) <= maxCaptionSize)
+	is.True(strings.HasSuffix(caption, `\!`+ellipsis)) // caption should end with escaped sentence
+
+	reply, ok, err := msg.overflow("channel", 42, maxCaptionSize)
+	is.NoErr(err)
+	is.True(ok)
+	is.True(strings.HasPrefix(reply.Text, `Книга о Go \(версия 1\.22\)\!`))
+
+	_, ok, err = msg.overflow("channel", 42, maxTextSize)
+	is.NoErr(err)
+	is.True(!ok) // description fits into text message
+}
+
+func TestMessageShortensTooLongHeader(t *testing.T) {
+	is := is.New(t)
+
+	msg := Message{
+		ImageURL: "imageurl",
+		Title:    strings.Repeat("Очень длинное название книги. ", 40),
+		Subtitle: strings.Repeat("Иванов И., Петров П., Сидоров С., ", 30),
+		Link:     "link",
+		Text:     "Описание книги.",
+	}
+
+	upload, err := msg.imageWithCaption("channel", tgbotapi.FileURL("imageurl"))
+	is.NoErr(err)
+
+	caption := upload.Caption
+	is.True(utf8.RuneCountInString(caption) <= maxCaptionSize)
+	is.True(strings.HasPrefix(caption, "*Очень длинное название книги\\. "))
+	is.True(strings.Contains(caption, ellipsis+"*")) // title should be shortened
+	is.True(strings.Contains(caption, "[Купить](link)"))
+	is.True(!strings.Contains(caption, "Описание"))
+
+	reply, ok, err := msg.overflow("channel", 42, maxCaptionSize)
+	is.NoErr(err)
+	is.True(ok) // description should be sent as reply entirely
+	is.Equal(reply.Text, `Описание книги\.`)
+
+	message, err := msg.textOnly("channel")
+	is.NoErr(err)
+	is.True(utf8.RuneCountInString(message.Text) <= maxTextSize)
+	is.True(strings.Contains(message.Text, "Описание")) // header and description fit into text message
+}
+
+func TestSplitText(t *testing.T) {
+	is := is.New(t)
+
+	head, rest := splitText("short", 10)
+	is.Equal(head, "short")
+	is.Equal(rest, "")
+
+	head, rest = splitText("First paragraph.\nSecond one is long", 30)
+	is.Equal(head, "First paragraph.")
+	is.Equal(rest, "Second one is long")
+
+	head, rest = splitText("One sentence. And another sentence", 25)
+	is.Equal(head, "One sentence.")
+	is.Equal(rest, "And another sentence")
+
+	head, rest = splitText("many words without punctuation", 20)
+	is.Equal(head, "many words without")
+	is.Equal(rest, "punctuation")
+
+	// escaped dot takes two runes, so it shouldn't be cut in the middle
+	head, rest = splitText("abcd.efgh", 5)
+	is.Equal(head, "abcd")
+	is.Equal(rest, ".efgh")
+}
+
+func TestMessageEditsCaption(t *testing.T) {
+	is := is.New(t)
+
+	msg := Message{
+		ImageURL: "imageurl",
+		Title:    "title",
+		Subtitle: "subtitle",
+		Link:     "link",
+		Text:     "text",
+	}
+
+	upload, err := msg.imageWithCaption("channel", tgbotapi.FileURL("imageurl"))
+	is.NoErr(err)
+
+	edit, err := msg.editCaption("channel", 42)
+	is.NoErr(err)
+
+	is.Equal(edit.ChannelUsername, "channel")
+	is.Equal(edit.MessageID, 42)
+	is.Equal(edit.ParseMode, tgbotapi.ModeMarkdownV2)
+	is.Equal(edit.Caption, upload.Caption) // edited caption should be the same as caption of new message
+}
+
+func TestMessageEditsMedia(t *testing.T) {
+	is := is.New(t)
+
+	msg := Message{
+		ImageURL: "imageurl",
+		Title:    "title",
+		Subtitle: "subtitle",
+		Link:     "link",
+		Text:     "text",
+	}
+
+	upload, err := msg.imageWithCaption("channel", tgbotapi.FileURL("imageurl"))
+	is.NoErr(err)
+
+	edit, err := msg.editMedia("channel", 42, tgbotapi.FileURL("imageurl"))
+	is.NoErr(err)
+
+	is.Equal(edit.ChannelUsername, "channel")
+	is.Equal(edit.MessageID, 42)
+
+	photo, ok := edit.Media.(tgbotapi.InputMediaPhoto)
+	is.True(ok)
+	is.Equal(photo.Media.SendData(), "imageurl")
+	is.Equal(photo.ParseMode, tgbotapi.ModeMarkdownV2)
+	is.Equal(photo.Caption, upload.Caption)
+}
+
+func TestMessageWithoutImage(t *testing.T) {
+	is := is.New(t)
+
+	msg := Message{
+		Title:    "title",
+		Subtitle: "subtitle",
+		Link:     "link",
+		Text:     "text",
+	}
+
+	upload, err := msg.imageWithCaption("channel", tgbotapi.FileURL("imageurl"))
+	is.NoErr(err)
+
+	text, err := msg.textOnly("channel")
+	is.NoErr(err)
+
+	is.Equal(text.ChannelUsername, "channel")
+	is.Equal(text.ParseMode, tgbotapi.ModeMarkdownV2)
+	is.Equal(text.Text, upload.Caption) // text should be the same as caption of message with image
+
+	edit, err := msg.editText("channel", 42)
 	is.NoErr(err)
 
-	is.Equal(utf8.RuneCountInString(upload.Caption), maxTelegramMessageSize)
+	is.Equal(edit.MessageID, 42)
+	is.Equal(edit.Text, upload.Caption)
 }
@@ -2,7 +2,10 @@ package telegram
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log"
+	"strings"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 )
@@ -23,13 +26,144 @@ func Authorize(ctx context.Context, token string) error {
 	return nil
 }
 
-// Send sends message to provided channel.
+// Send sends message to provided channel and returns id of sent message, so it can be edited later.
 // It is required to add bot to that channel.
-func Send(ctx context.Context, channel string, msg Message) error {
-	imageWithCaption, err := msg.imageWithCaption(channel)
+//
+// Cover is downloaded and uploaded to telegram as JPEG. Message is sent without image
+// if book has no cover or it can't be used, withImage reports which way it was sent.
+// Description that doesn't fit into message is sent as reply to it.
+//
+// Requests are retried when rate limit is exceeded or telegram is unavailable.
+// Errors of telegram api are returned as *Error, see ErrChatNotFound and others for permanent ones.
+func Send(ctx context.Context, channel string, msg Message) (messageID int, withImage bool, err error) {
+	sent, withImage, err := send(ctx, channel, msg)
+	if err != nil {
+		return 0, false, err
+	}
+
+	limit := maxTextSize
+	if withImage {
+		limit = maxCaptionSize
+	}
+
+	// message is already published, so failed reply doesn't fail publication
+	reply, ok, err := msg.overflow(channel, sent.MessageID, limit)
+	if err == nil && ok {
+		_, err = request(ctx, reply)
+	}
+	if err != nil {
+		log.Printf("cannot send the rest of description as reply to message %d: %v", sent.MessageID, err)
+	}
+
+	return sent.MessageID, withImage, nil
+}
+
+// send sends message with cover or without it and returns sent message and whether it has image.
+func send(ctx context.Context, channel string, msg Message) (tgbotapi.Message, bool, error) {
+	image, err := downloadImage(ctx, msg.ImageURL)
+	if err == nil {
+		var imageWithCaption tgbotapi.PhotoConfig
+		imageWithCaption, err = msg.imageWithCaption(channel, coverFile(image))
+		if err != nil {
+			return tgbotapi.Message{}, false, fmt.Errorf("cannot create image with caption: %w", err)
+		}
+
+		var sent tgbotapi.Message
+		sent, err = request(ctx, imageWithCaption)
+		if !errors.Is(err, ErrBadImage) {
+			return sent, true, err
+		}
+	}
+
+	if !errors.Is(err, errNoImage) {
+		log.Printf("cannot use cover %s, sending message without it: %v", msg.ImageURL, err)
+	}
+
+	text, err := msg.textOnly(channel)
 	if err != nil {
-		return fmt.Errorf("cannot create image with caption: %w", err)
+		return tgbotapi.Message{}, false, fmt.Errorf("cannot create text message: %w", err)
 	}
-	_, err = api.Send(imageWithCaption)
+
+	sent, err := request(ctx, text)
+	return sent, false, err
+}
+
+// EditText replaces text of message previously sent to channel without image.
+// Reply with the rest of description isn't edited.
+func EditText(ctx context.Context, channel string, messageID int, msg Message) error {
+	edit, err := msg.editText(channel, messageID)
+	if err != nil {
+		return fmt.Errorf("cannot create text: %w", err)
+	}
+
+	_, err = request(ctx, edit)
+	return ignoreNotModified(err)
+}
+
+// EditCaption replaces text of message previously sent to channel, image stays the same.
+// Reply with the rest of description isn't edited.
+//
+// Message turned out to be sent without image is edited with EditText.
+func EditCaption(ctx context.Context, channel string, messageID int, msg Message) error {
+	edit, err := msg.editCaption(channel, messageID)
+	if err != nil {
+		return fmt.Errorf("cannot create caption: %w", err)
+	}
+
+	_, err = request(ctx, edit)
+	if errors.Is(err, ErrNoCaption) {
+		return EditText(ctx, channel, messageID, msg)
+	}
+
+	return ignoreNotModified(err)
+}
+
+// EditMedia replaces both image and text of message previously sent to channel.
+// Only text is replaced if new cover can't be used or message was sent without image,
+// since telegram can't add image to text message.
+func EditMedia(ctx context.Context, channel string, messageID int, msg Message) error {
+	image, err := downloadImage(ctx, msg.ImageURL)
+	if err == nil {
+		var edit tgbotapi.EditMessageMediaConfig
+		edit, err = msg.editMedia(channel, messageID, coverFile(image))
+		if err != nil {
+			return fmt.Errorf("cannot create image with caption: %w", err)
+		}
+
+		_, err = request(ctx, edit)
+		if errors.Is(err, ErrNoMedia) {
+			return EditText(ctx, channel, messageID, msg)
+		}
+		if !errors.Is(err, ErrBadImage) {
+			return ignoreNotModified(err)
+		}
+	}
+
+	log.Printf("cannot use cover %s, editing caption only: %v", msg.ImageURL, err)
+	return EditCaption(ctx, channel, messageID, msg)
+}
+
+// coverFile is JPEG cover uploaded to telegram.
+func coverFile(image []byte) tgbotapi.FileBytes {
+	return tgbotapi.FileBytes{Name: "cover.jpg", Bytes: image}
+}
+
+// request sends request to telegram, retrying it on temporary failures.
+func request(ctx context.Context, c tgbotapi.Chattable) (tgbotapi.Message, error) {
+	var sent tgbotapi.Message
+	err := retry(ctx, func() (err error) {
+		sent, err = api.Send(c)
+		return err
+	})
+
+	return sent, err
+}
+
+// ignoreNotModified ignores error returned by telegram when message is edited to the same content.
+func ignoreNotModified(err error) error {
+	if err != nil && strings.Contains(err.Error(), "message is not modified") {
+		return nil
+	}
+
 	return err
 }
@@ -0,0 +1,117 @@
+package telegram
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"sync"
+	"testing"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/matryer/is"
+)
+
+// fakeAPI is telegram bot api server answering methods with canned responses.
+// It records names of called methods.
+type fakeAPI struct {
+	cover     []byte
+	responses map[string]string
+
+	mu      sync.Mutex
+	methods []string
+}
+
+func (f *fakeAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/cover.png" {
+		_, _ = w.Write(f.cover)
+		return
+	}
+
+	method := path.Base(r.URL.Path)
+	f.mu.Lock()
+	f.methods = append(f.methods, method)
+	f.mu.Unlock()
+
+	response, ok := f.responses[method]
+	if !ok {
+		response = `{"ok":true,"result":{"message_id":1,"date":0,"chat":{"id":1,"type":"channel"}}}`
+	}
+	_, _ = w.Write([]byte(response))
+}
+
+func (f *fakeAPI) called() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.methods
+}
+
+func useFakeAPI(t *testing.T, responses map[string]string) (*fakeAPI, string) {
+	fake := &fakeAPI{cover: encodePNG(t, 300, 400), responses: map[string]string{
+		"getMe": `{"ok":true,"result":{"id":1,"is_bot":true,"first_name":"itbooks","username":"itbooks_bot"}}`,
+	}}
+	for method, response := range responses {
+		fake.responses[method] = response
+	}
+
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	botAPI, err := tgbotapi.NewBotAPIWithAPIEndpoint("token", server.URL+"/bot%s/%s")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	oldAPI := api
+	api = botAPI
+	t.Cleanup(func() { api = oldAPI })
+
+	fake.methods = nil // getMe of authorization
+	return fake, server.URL
+}
+
+func TestEditMediaOfTextMessageEditsText(t *testing.T) {
+	is := is.New(t)
+
+	fake, url := useFakeAPI(t, map[string]string{
+		"editMessageMedia":   `{"ok":false,"error_code":400,"description":"Bad Request: there is no media in the message to edit"}`,
+		"editMessageCaption": `{"ok":false,"error_code":400,"description":"Bad Request: there is no caption in the message to edit"}`,
+	})
+
+	msg := Message{ImageURL: url + "/cover.png", Title: "title", Link: "link", Text: "text"}
+	err := EditMedia(context.Background(), "channel", 1, msg)
+
+	is.NoErr(err)
+	is.Equal(fake.called(), []string{"editMessageMedia", "editMessageText"})
+}
+
+func TestEditCaptionOfTextMessageEditsText(t *testing.T) {
+	is := is.New(t)
+
+	fake, _ := useFakeAPI(t, map[string]string{
+		"editMessageCaption": `{"ok":false,"error_code":400,"description":"Bad Request: there is no caption in the message to edit"}`,
+	})
+
+	msg := Message{Title: "title", Link: "link", Text: "text"}
+	err := EditCaption(context.Background(), "channel", 1, msg)
+
+	is.NoErr(err)
+	is.Equal(fake.called(), []string{"editMessageCaption", "editMessageText"})
+}
+
+func TestSendReportsImage(t *testing.T) {
+	is := is.New(t)
+
+	fake, url := useFakeAPI(t, nil)
+
+	_, withImage, err := Send(context.Background(), "channel", Message{ImageURL: url + "/cover.png", Title: "title", Link: "link", Text: "text"})
+	is.NoErr(err)
+	is.True(withImage)
+
+	_, withImage, err = Send(context.Background(), "channel", Message{Title: "title", Link: "link", Text: "text"})
+	is.NoErr(err)
+	is.True(!withImage)
+
+	is.Equal(fake.called(), []string{"sendPhoto", "sendMessage"})
+}